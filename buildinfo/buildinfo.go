@@ -0,0 +1,133 @@
+// Package buildinfo exposes version information stamped into the binary at
+// link time, e.g.
+//
+//	go build -ldflags "-X <module>/buildinfo.Version=1.4.0 \
+//		-X <module>/buildinfo.Commit=$(git rev-parse HEAD) \
+//		-X <module>/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// MetricName is the name of the gauge written by WriteMetric.
+const MetricName = "build_info"
+
+// Values set via -ldflags. Commit falls back to the VCS revision recorded
+// by the Go toolchain when not set.
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	var settings []debug.BuildSetting
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		settings = bi.Settings
+	}
+	return infoFrom(settings)
+}
+
+// infoFrom builds Info from the ldflags variables, falling back to the VCS
+// stamp in settings for values left unset.
+func infoFrom(settings []debug.BuildSetting) Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+	for _, s := range settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = s.Value
+			}
+		}
+	}
+	return info
+}
+
+// Labels returns the build information as metric labels, suitable for a
+// build_info gauge fixed at 1 in another metrics system.
+func (i Info) Labels() map[string]string {
+	return map[string]string{
+		"version":   i.Version,
+		"commit":    i.Commit,
+		"date":      i.Date,
+		"goversion": i.GoVersion,
+	}
+}
+
+// Handler serves the build information as JSON, e.g. on /version.
+func Handler() http.Handler {
+	body, _ := json.Marshal(Get())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetric writes the build_info gauge, with the build information as
+// labels and a constant value of 1, to w in the Prometheus text exposition
+// format. Dashboards join on it to correlate changes with deploys.
+func (i Info) WriteMetric(w io.Writer) error {
+	labels := i.Labels()
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s Build information of the running binary.\n", MetricName)
+	fmt.Fprintf(&b, "# TYPE %s gauge\n", MetricName)
+	b.WriteString(MetricName)
+	b.WriteByte('{')
+	for n, name := range names {
+		if n > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, labelEscaper.Replace(labels[name]))
+	}
+	b.WriteString("} 1\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// MetricHandler serves the build_info gauge in the Prometheus text
+// exposition format. It can be mounted on its own scrape path or its
+// output appended to an existing /metrics response via WriteMetric.
+func MetricHandler() http.Handler {
+	var b strings.Builder
+	Get().WriteMetric(&b)
+	body := b.String()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		io.WriteString(w, body)
+	})
+}
@@ -0,0 +1,96 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func setVars(t *testing.T, version, commit, date string) {
+	t.Helper()
+	oldVersion, oldCommit, oldDate := Version, Commit, Date
+	Version, Commit, Date = version, commit, date
+	t.Cleanup(func() { Version, Commit, Date = oldVersion, oldCommit, oldDate })
+}
+
+var vcs = []debug.BuildSetting{
+	{Key: "vcs", Value: "git"},
+	{Key: "vcs.revision", Value: "abc123"},
+	{Key: "vcs.time", Value: "2026-10-01T12:00:00Z"},
+}
+
+func TestLdflagsOverVCS(t *testing.T) {
+	setVars(t, "1.4.0", "def456", "2026-10-14T09:00:00Z")
+	got := infoFrom(vcs)
+	want := Info{Version: "1.4.0", Commit: "def456", Date: "2026-10-14T09:00:00Z", GoVersion: runtime.Version()}
+	if got != want {
+		t.Errorf("infoFrom = %+v, want %+v", got, want)
+	}
+}
+
+func TestVCSFallback(t *testing.T) {
+	setVars(t, "dev", "", "")
+	got := infoFrom(vcs)
+	if got.Commit != "abc123" || got.Date != "2026-10-01T12:00:00Z" || got.Version != "dev" {
+		t.Errorf("infoFrom = %+v, want VCS commit and date", got)
+	}
+
+	setVars(t, "dev", "", "2026-10-14T09:00:00Z")
+	got = infoFrom(vcs)
+	if got.Commit != "abc123" || got.Date != "2026-10-14T09:00:00Z" {
+		t.Errorf("infoFrom = %+v, want VCS commit with ldflags date", got)
+	}
+
+	setVars(t, "dev", "", "")
+	if got := infoFrom(nil); got.Commit != "" || got.Date != "" {
+		t.Errorf("infoFrom(nil) = %+v, want empty commit and date", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	setVars(t, "1.4.0", "def456", "2026-10-14T09:00:00Z")
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var got Info
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != "1.4.0" || got.Commit != "def456" || got.Date != "2026-10-14T09:00:00Z" || got.GoVersion != runtime.Version() {
+		t.Errorf("body = %+v", got)
+	}
+}
+
+func TestWriteMetric(t *testing.T) {
+	info := Info{Version: `1.4.0-"rc"`, Commit: "def456", Date: `a\b`, GoVersion: "go1.21\n"}
+	var b strings.Builder
+	if err := info.WriteMetric(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP build_info Build information of the running binary.\n" +
+		"# TYPE build_info gauge\n" +
+		`build_info{commit="def456",date="a\\b",goversion="go1.21\n",version="1.4.0-\"rc\""} 1` + "\n"
+	if b.String() != want {
+		t.Errorf("WriteMetric =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestMetricHandler(t *testing.T) {
+	setVars(t, "1.4.0", "def456", "2026-10-14T09:00:00Z")
+	w := httptest.NewRecorder()
+	MetricHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), `version="1.4.0"} 1`) || !strings.Contains(w.Body.String(), `commit="def456"`) {
+		t.Errorf("body missing build_info sample:\n%s", w.Body.String())
+	}
+}
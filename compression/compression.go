@@ -0,0 +1,296 @@
+// Package compression provides HTTP middleware that gzip-compresses
+// responses for clients that accept it.
+package compression
+
+import (
+	"bufio"
+	"compress/gzip"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultMinSize is the response size below which compression is skipped
+// when Options.MinSize is zero.
+const DefaultMinSize = 1024
+
+// DefaultContentTypes are compressed when Options.ContentTypes is empty.
+var DefaultContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// Options configures the middleware.
+type Options struct {
+	// Level is the gzip compression level. Zero selects
+	// gzip.DefaultCompression.
+	Level int
+	// MinSize is the number of body bytes that must be written before a
+	// response is compressed. Smaller responses are sent as is.
+	MinSize int
+	// ContentTypes lists the media types to compress. An entry ending in
+	// "/*" matches every subtype.
+	ContentTypes []string
+}
+
+// Middleware returns a function wrapping a handler so that responses of a
+// configured content type and at least MinSize bytes are gzip-encoded when
+// the request's Accept-Encoding allows it. Responses that already carry a
+// Content-Encoding are left alone.
+//
+// Middleware panics if opts.Level is not a valid gzip level.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	level := opts.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		panic("compression: " + err.Error())
+	}
+	minSize := opts.MinSize
+	if minSize <= 0 {
+		minSize = DefaultMinSize
+	}
+	types := opts.ContentTypes
+	if len(types) == 0 {
+		types = DefaultContentTypes
+	}
+
+	pool := &sync.Pool{New: func() interface{} {
+		gz, _ := gzip.NewWriterLevel(nil, level)
+		return gz
+	}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			// Ranges address the uncompressed representation, so range
+			// requests are served as is.
+			if r.Method == http.MethodHead || r.Header.Get("Range") != "" ||
+				!acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &responseWriter{
+				ResponseWriter: w,
+				pool:           pool,
+				minSize:        minSize,
+				types:          types,
+			}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding value allows gzip. An
+// explicit gzip entry takes precedence over "*", so "*;q=0, gzip" accepts
+// gzip and "gzip;q=0, *" refuses it.
+func acceptsGzip(header string) bool {
+	explicit, wildcard := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip":
+			explicit = qvalue(params)
+		case "*":
+			wildcard = qvalue(params)
+		}
+	}
+	if explicit >= 0 {
+		return explicit > 0
+	}
+	return wildcard > 0
+}
+
+// qvalue returns the q parameter from an Accept-Encoding entry's
+// parameters, defaulting to 1.
+func qvalue(params string) float64 {
+	for _, p := range strings.Split(params, ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if strings.EqualFold(strings.TrimSpace(k), "q") {
+			q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return 0
+			}
+			return q
+		}
+	}
+	return 1
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	pool    *sync.Pool
+	minSize int
+	types   []string
+
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+// WriteHeader records the final status for when the compression decision
+// is made. Informational 1xx responses such as 103 Early Hints are sent
+// straight away and do not count as the final status.
+func (w *responseWriter) WriteHeader(status int) {
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		if len(w.buf)+len(p) < w.minSize {
+			w.buf = append(w.buf, p...)
+			return len(p), nil
+		}
+		// Decide without copying p, so one large Write is never held in
+		// memory twice.
+		if err := w.decide(p); err != nil {
+			return 0, err
+		}
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide commits to compressing or not, then writes the header and any
+// buffered body bytes. next is the pending write, if any; it is used for
+// content sniffing and the size threshold but left to the caller to write.
+func (w *responseWriter) decide(next []byte) error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf)+len(next) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.sniff(next)))
+	}
+	if len(w.buf)+len(next) >= w.minSize && w.compressible() {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		// Byte ranges of the compressed stream are not offered.
+		h.Del("Accept-Ranges")
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// sniff returns up to the 512 leading body bytes http.DetectContentType
+// considers, drawn from the buffer followed by next.
+func (w *responseWriter) sniff(next []byte) []byte {
+	const sniffLen = 512
+	if len(w.buf) >= sniffLen || len(next) == 0 {
+		return w.buf
+	}
+	if len(w.buf) == 0 {
+		return next
+	}
+	b := make([]byte, 0, sniffLen)
+	b = append(b, w.buf...)
+	n := sniffLen - len(b)
+	if n > len(next) {
+		n = len(next)
+	}
+	return append(b, next[:n]...)
+}
+
+func (w *responseWriter) compressible() bool {
+	switch {
+	case w.status < http.StatusOK,
+		w.status == http.StatusNoContent,
+		w.status == http.StatusPartialContent,
+		w.status == http.StatusNotModified:
+		return false
+	}
+	if w.Header().Get("Content-Encoding") != "" || w.Header().Get("Content-Range") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, t := range w.types {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush sends any buffered data to the client, committing to the current
+// compression decision.
+func (w *responseWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.decide(nil)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets protocols such as websockets take over the connection.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) close() {
+	if !w.decided && w.status != 0 {
+		w.decide(nil)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(nil)
+		w.pool.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,315 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+var snapshot = strings.Repeat(`{"warehouse":"COMPUTE_WH","credits":1.5},`, 200)
+
+func handler(status int, contentType, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(status)
+		// Write in pieces to exercise buffering across calls.
+		body := body
+		for len(body) > 0 {
+			n := 100
+			if n > len(body) {
+				n = len(body)
+			}
+			w.Write([]byte(body[:n]))
+			body = body[n:]
+		}
+	})
+}
+
+func do(t *testing.T, h http.Handler, method, acceptEncoding string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	r := httptest.NewRequest(method, "/snapshot", nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	Middleware(Options{})(h).ServeHTTP(w, r)
+
+	body := w.Body.Bytes()
+	if w.Header().Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+		if body, err = io.ReadAll(zr); err != nil {
+			t.Fatalf("reading gzip body: %v", err)
+		}
+	}
+	return w, string(body)
+}
+
+func TestGzipRoundTrip(t *testing.T) {
+	w, body := do(t, handler(http.StatusOK, "application/json; charset=utf-8", snapshot), http.MethodGet, "deflate, gzip")
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if body != snapshot {
+		t.Error("decompressed body differs from original")
+	}
+	if w.Body.Len() >= len(snapshot) {
+		t.Errorf("compressed size %d not smaller than %d", w.Body.Len(), len(snapshot))
+	}
+	if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want Accept-Encoding", got)
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		status         int
+		contentType    string
+		body           string
+	}{
+		{"below MinSize", http.MethodGet, "gzip", http.StatusOK, "application/json", `{"ok":true}`},
+		{"not accepted", http.MethodGet, "br", http.StatusOK, "application/json", snapshot},
+		{"no Accept-Encoding", http.MethodGet, "", http.StatusOK, "application/json", snapshot},
+		{"gzip refused with q=0", http.MethodGet, "gzip;q=0, *", http.StatusOK, "application/json", snapshot},
+		{"excluded content type", http.MethodGet, "gzip", http.StatusOK, "image/png", snapshot},
+		{"HEAD request", http.MethodHead, "gzip", http.StatusOK, "application/json", ""},
+		{"no content", http.MethodGet, "gzip", http.StatusNoContent, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, body := do(t, handler(tt.status, tt.contentType, tt.body), tt.method, tt.acceptEncoding)
+			if got := w.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding = %q, want none", got)
+			}
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestWildcardAcceptEncoding(t *testing.T) {
+	w, body := do(t, handler(http.StatusCreated, "text/csv", snapshot), http.MethodGet, "br, *")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Code != http.StatusCreated || body != snapshot {
+		t.Errorf("status = %d encoding = %q, want gzip-encoded 201", w.Code, w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestExistingContentEncodingUntouched(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "br")
+		w.Write([]byte(snapshot))
+	})
+	w, _ := do(t, h, http.MethodGet, "gzip, br")
+	if got := w.Header().Get("Content-Encoding"); got != "br" {
+		t.Errorf("Content-Encoding = %q, want br", got)
+	}
+	if w.Body.String() != snapshot {
+		t.Error("body was modified")
+	}
+}
+
+func TestSniffsContentType(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("plain text line\n", 100)))
+	})
+	w, _ := do(t, h, http.MethodGet, "gzip")
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+		t.Errorf("Content-Type = %q, want sniffed text/plain", got)
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Error("sniffed text/plain response was not compressed")
+	}
+}
+
+func TestLargeWriteNotBuffered(t *testing.T) {
+	var buffered int
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(snapshot))
+		buffered = cap(w.(*responseWriter).buf)
+	})
+	w, body := do(t, h, http.MethodGet, "gzip")
+	if buffered != 0 {
+		t.Errorf("single large Write buffered %d bytes, want 0", buffered)
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" || body != snapshot {
+		t.Error("large single Write not compressed correctly")
+	}
+}
+
+func TestInformationalThenFinalStatus(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(snapshot))
+	})
+	srv := httptest.NewServer(Middleware(Options{})(h))
+	defer srv.Close()
+
+	var early []int
+	trace := &httptrace.ClientTrace{Got1xxResponse: func(code int, _ textproto.MIMEHeader) error {
+		early = append(early, code)
+		return nil
+	}}
+	req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := srv.Client().Transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if len(early) != 1 || early[0] != http.StatusEarlyHints {
+		t.Errorf("informational responses = %v, want [103]", early)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("status = %d, want 201", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+}
+
+func TestFlushCommitsDecision(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(snapshot))
+	})
+	w, body := do(t, h, http.MethodGet, "gzip")
+	if !w.Flushed {
+		t.Error("Flush not passed through")
+	}
+	if w.Header().Get("Content-Encoding") != "" || body != "data: 1\n\n"+snapshot {
+		t.Error("response flushed below MinSize should stay uncompressed")
+	}
+}
+
+func TestWriterReuse(t *testing.T) {
+	mw := Middleware(Options{MinSize: 10})
+	h := mw(handler(http.StatusOK, "application/json", snapshot))
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if b, _ := io.ReadAll(zr); string(b) != snapshot {
+			t.Fatalf("request %d: body mismatch", i)
+		}
+	}
+}
+
+func TestInvalidLevelPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Middleware with level 42 did not panic")
+		}
+	}()
+	Middleware(Options{Level: 42})
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0", false},
+		{"*", true},
+		{"br, *;q=0.1", true},
+		{"*;q=0", false},
+		{"*;q=0, gzip", true},
+		{"gzip, *;q=0", true},
+		{"gzip;q=0, *", false},
+		{"*, gzip;q=0", false},
+		{"br, deflate", false},
+		{"gzip;q=bogus", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestServeContentRange(t *testing.T) {
+	content := strings.Repeat("0123456789abcdef", 500)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "history.txt", time.Time{}, strings.NewReader(content))
+	})
+	srv := Middleware(Options{})(h)
+
+	r := httptest.NewRequest(http.MethodGet, "/history.txt", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("Range", "bytes=0-3999")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", w.Code)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q on a partial response, want none", got)
+	}
+	if got, want := w.Header().Get("Content-Range"), "bytes 0-3999/8000"; got != want {
+		t.Errorf("Content-Range = %q, want %q", got, want)
+	}
+	if w.Body.String() != content[:4000] {
+		t.Errorf("body is %d bytes, want the first 4000 bytes of the content", w.Body.Len())
+	}
+
+	// A full response is compressed and no longer advertises ranges.
+	r = httptest.NewRequest(http.MethodGet, "/history.txt", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("full response Content-Encoding = %q, want gzip", got)
+	}
+	if got := w.Header().Get("Accept-Ranges"); got != "" {
+		t.Errorf("compressed response Accept-Ranges = %q, want none", got)
+	}
+}
+
+func TestContentRangeNotCompressed(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Range", "bytes 0-7999/16000")
+		w.Write([]byte(snapshot))
+	})
+	w, _ := do(t, h, http.MethodGet, "gzip")
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q with Content-Range set, want none", got)
+	}
+}
@@ -0,0 +1,172 @@
+// Package cors provides configurable Cross-Origin Resource Sharing
+// middleware for browser-facing endpoints.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMethods are allowed when Options.AllowedMethods is empty.
+var DefaultMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodPost,
+	http.MethodDelete,
+}
+
+// Options configures the middleware.
+type Options struct {
+	// AllowedOrigins lists the origins permitted to make cross-origin
+	// requests. "*" allows any origin, and an entry such as
+	// "https://*.example.com" allows any subdomain of example.com.
+	AllowedOrigins []string
+	// AllowedMethods lists the methods allowed in preflight responses.
+	// DefaultMethods is used when empty.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers allowed in preflight
+	// responses. When empty the headers the browser asks for are reflected.
+	AllowedHeaders []string
+	// ExposedHeaders lists response headers the browser may expose to
+	// scripts.
+	ExposedHeaders []string
+	// AllowCredentials permits cookies and authorization headers from
+	// origins listed explicitly or matched by a subdomain wildcard; the
+	// origin is then echoed rather than sent as "*". Origins allowed only by
+	// "*" never receive credentials, so a catch-all cannot be combined with
+	// credentialed access.
+	AllowCredentials bool
+	// MaxAge controls how long browsers may cache a preflight response.
+	// Zero omits the header.
+	MaxAge time.Duration
+}
+
+type cors struct {
+	next        http.Handler
+	anyOrigin   bool
+	origins     map[string]struct{}
+	wildcards   []wildcard
+	methods     string
+	headers     string
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+type wildcard struct {
+	prefix, suffix string
+}
+
+func (w wildcard) match(origin string) bool {
+	return len(origin) >= len(w.prefix)+len(w.suffix) &&
+		strings.HasPrefix(origin, w.prefix) &&
+		strings.HasSuffix(origin, w.suffix)
+}
+
+// Middleware returns a function wrapping a handler with CORS handling
+// configured by opts. Preflight requests from allowed origins are answered
+// directly with 204 No Content and never reach the wrapped handler.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	c := cors{
+		origins:     make(map[string]struct{}),
+		methods:     strings.Join(opts.AllowedMethods, ", "),
+		headers:     strings.Join(opts.AllowedHeaders, ", "),
+		exposed:     strings.Join(opts.ExposedHeaders, ", "),
+		credentials: opts.AllowCredentials,
+	}
+	for _, o := range opts.AllowedOrigins {
+		o = strings.ToLower(o)
+		switch {
+		case o == "*":
+			c.anyOrigin = true
+		case strings.Contains(o, "*"):
+			i := strings.IndexByte(o, '*')
+			c.wildcards = append(c.wildcards, wildcard{o[:i], o[i+1:]})
+		default:
+			c.origins[o] = struct{}{}
+		}
+	}
+	if c.methods == "" {
+		c.methods = strings.Join(DefaultMethods, ", ")
+	}
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(opts.MaxAge / time.Second))
+	}
+
+	return func(next http.Handler) http.Handler {
+		c := c
+		c.next = next
+		return &c
+	}
+}
+
+func (c *cors) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	preflight := origin != "" && r.Method == http.MethodOptions &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if preflight {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+	}
+
+	listed := origin != "" && c.listed(origin)
+	if origin == "" || !(listed || c.anyOrigin) {
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	switch {
+	case c.credentials && listed:
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+	case c.anyOrigin:
+		h.Set("Access-Control-Allow-Origin", "*")
+	default:
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if !preflight {
+		if c.exposed != "" {
+			h.Set("Access-Control-Expose-Headers", c.exposed)
+		}
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	h.Set("Access-Control-Allow-Methods", c.methods)
+	if c.headers != "" {
+		h.Set("Access-Control-Allow-Headers", c.headers)
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		h.Set("Access-Control-Allow-Headers", requested)
+	}
+	if c.maxAge != "" {
+		h.Set("Access-Control-Max-Age", c.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listed reports whether origin is allowed by an explicit entry or a
+// subdomain wildcard, as opposed to only by "*".
+func (c *cors) listed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if _, ok := c.origins[origin]; ok {
+		return true
+	}
+	for _, w := range c.wildcards {
+		if w.match(origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+func serve(opts Options, method, origin string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/snapshot", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	Middleware(opts)(okHandler).ServeHTTP(w, r)
+	return w
+}
+
+func preflight(method string) map[string]string {
+	return map[string]string{
+		"Access-Control-Request-Method":  method,
+		"Access-Control-Request-Headers": "Authorization, X-Hbo-Caller",
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	opts := Options{AllowedOrigins: []string{"https://app.example.org", "https://*.example.com"}}
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{"https://app.example.org", "https://app.example.org"},
+		{"HTTPS://APP.EXAMPLE.ORG", "HTTPS://APP.EXAMPLE.ORG"},
+		{"https://foo.example.com", "https://foo.example.com"},
+		{"https://a.b.example.com", "https://a.b.example.com"},
+		{"https://example.com", ""},
+		{"https://evilexample.com", ""},
+		{"http://foo.example.com", ""},
+		{"https://evil.example", ""},
+	}
+	for _, tt := range tests {
+		w := serve(opts, http.MethodGet, tt.origin, nil)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+			t.Errorf("origin %q: Allow-Origin = %q, want %q", tt.origin, got, tt.want)
+		}
+		if w.Code != http.StatusOK || w.Body.String() != "ok" {
+			t.Errorf("origin %q: handler not called, status %d", tt.origin, w.Code)
+		}
+	}
+}
+
+func TestAnyOrigin(t *testing.T) {
+	w := serve(Options{AllowedOrigins: []string{"*"}}, http.MethodGet, "https://evil.example", nil)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Allow-Origin = %q, want *", got)
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	opts := Options{
+		AllowedOrigins:   []string{"*", "https://app.example.org", "https://*.example.com"},
+		AllowCredentials: true,
+	}
+	tests := []struct {
+		origin      string
+		origins     string
+		credentials string
+	}{
+		{"https://evil.example", "*", ""},
+		{"https://app.example.org", "https://app.example.org", "true"},
+		{"https://foo.example.com", "https://foo.example.com", "true"},
+	}
+	for _, tt := range tests {
+		for _, method := range []string{http.MethodGet, http.MethodOptions} {
+			var header map[string]string
+			if method == http.MethodOptions {
+				header = preflight(http.MethodPost)
+			}
+			h := serve(opts, method, tt.origin, header).Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.origins {
+				t.Errorf("%s %q: Allow-Origin = %q, want %q", method, tt.origin, got, tt.origins)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+				t.Errorf("%s %q: Allow-Credentials = %q, want %q", method, tt.origin, got, tt.credentials)
+			}
+		}
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	opts := Options{
+		AllowedOrigins: []string{"https://*.example.com"},
+		MaxAge:         10 * time.Minute,
+	}
+	w := serve(opts, http.MethodOptions, "https://foo.example.com", preflight(http.MethodPut))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("preflight reached handler, body %q", w.Body.String())
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":  "https://foo.example.com",
+		"Access-Control-Allow-Methods": "GET, HEAD, PUT, PATCH, POST, DELETE",
+		"Access-Control-Allow-Headers": "Authorization, X-Hbo-Caller",
+		"Access-Control-Max-Age":       "600",
+	}
+	for k, v := range want {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestPreflightConfiguredLists(t *testing.T) {
+	opts := Options{
+		AllowedOrigins: []string{"https://app.example.org"},
+		AllowedMethods: []string{http.MethodGet},
+		AllowedHeaders: []string{"Authorization"},
+	}
+	h := serve(opts, http.MethodOptions, "https://app.example.org", preflight(http.MethodGet)).Header()
+	if got := h.Get("Access-Control-Allow-Methods"); got != "GET" {
+		t.Errorf("Allow-Methods = %q, want GET", got)
+	}
+	if got := h.Get("Access-Control-Allow-Headers"); got != "Authorization" {
+		t.Errorf("Allow-Headers = %q, want Authorization", got)
+	}
+	if got := h.Get("Access-Control-Max-Age"); got != "" {
+		t.Errorf("Max-Age = %q, want unset", got)
+	}
+}
+
+func TestPreflightDisallowedOrigin(t *testing.T) {
+	opts := Options{AllowedOrigins: []string{"https://app.example.org"}}
+	w := serve(opts, http.MethodOptions, "https://evil.example", preflight(http.MethodDelete))
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("status = %d body = %q, want bare 204", w.Code, w.Body.String())
+	}
+	for _, k := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods", "Access-Control-Allow-Headers"} {
+		if got := w.Header().Get(k); got != "" {
+			t.Errorf("%s = %q, want unset", k, got)
+		}
+	}
+}
+
+func TestOptionsWithoutOriginReachesHandler(t *testing.T) {
+	w := serve(Options{AllowedOrigins: []string{"*"}}, http.MethodOptions, "", preflight(http.MethodGet))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("status = %d body = %q, want handler response", w.Code, w.Body.String())
+	}
+}
+
+func TestExposedHeaders(t *testing.T) {
+	opts := Options{AllowedOrigins: []string{"*"}, ExposedHeaders: []string{"X-Request-Id", "X-Trace-Id"}}
+	h := serve(opts, http.MethodGet, "https://app.example.org", nil).Header()
+	if got := h.Get("Access-Control-Expose-Headers"); got != "X-Request-Id, X-Trace-Id" {
+		t.Errorf("Expose-Headers = %q", got)
+	}
+}
+
+func TestVary(t *testing.T) {
+	opts := Options{AllowedOrigins: []string{"https://app.example.org"}}
+	tests := []struct {
+		name   string
+		method string
+		origin string
+		header map[string]string
+		want   []string
+	}{
+		{"no origin", http.MethodGet, "", nil, []string{"Origin"}},
+		{"disallowed", http.MethodGet, "https://evil.example", nil, []string{"Origin"}},
+		{"allowed", http.MethodGet, "https://app.example.org", nil, []string{"Origin"}},
+		{"preflight", http.MethodOptions, "https://app.example.org", preflight(http.MethodGet),
+			[]string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}},
+	}
+	for _, tt := range tests {
+		got := serve(opts, tt.method, tt.origin, tt.header).Header().Values("Vary")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Vary = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
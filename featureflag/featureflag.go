@@ -0,0 +1,340 @@
+// Package featureflag provides boolean feature flags loaded from files,
+// environment variables or a remote endpoint and refreshed on a TTL, so
+// behavior can be toggled per environment without a deploy.
+//
+// Flag names are case-insensitive and treat '-', '.' and '_' alike, so the
+// flag "new-collector" is set by a file key "new_collector" or the
+// environment variable FEATURE_NEW_COLLECTOR.
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source loads the full set of flag values.
+type Source interface {
+	Load(ctx context.Context) (map[string]bool, error)
+}
+
+// SourceFunc adapts an ordinary function to the Source interface.
+type SourceFunc func(ctx context.Context) (map[string]bool, error)
+
+// Load calls f(ctx).
+func (f SourceFunc) Load(ctx context.Context) (map[string]bool, error) {
+	return f(ctx)
+}
+
+// File returns a Source reading a JSON object of flag names to booleans
+// from path. Entries whose value is not a boolean, or a string Env would
+// accept, are skipped.
+func File(path string) Source {
+	return SourceFunc(func(ctx context.Context) (map[string]bool, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		flags, err := decode(b)
+		if err != nil {
+			return nil, fmt.Errorf("featureflag: parsing %s: %w", path, err)
+		}
+		return flags, nil
+	})
+}
+
+// Env returns a Source reading every environment variable starting with
+// prefix, e.g. "FEATURE_". The rest of the variable name is the flag name.
+// Values accepted by strconv.ParseBool are recognized, as are yes/no and
+// on/off in any case. A variable with any other value is skipped, so one
+// typo cannot fail the load and, through Layered, discard every other
+// source's flags.
+func Env(prefix string) Source {
+	return SourceFunc(func(ctx context.Context) (map[string]bool, error) {
+		flags := make(map[string]bool)
+		for _, kv := range os.Environ() {
+			k, v, _ := strings.Cut(kv, "=")
+			name, ok := strings.CutPrefix(k, prefix)
+			if !ok || name == "" {
+				continue
+			}
+			if b, ok := parseBool(v); ok {
+				flags[name] = b
+			}
+		}
+		return flags, nil
+	})
+}
+
+func parseBool(s string) (bool, bool) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "yes", "on":
+		return true, true
+	case "no", "off":
+		return false, true
+	}
+	b, err := strconv.ParseBool(s)
+	return b, err == nil
+}
+
+// HTTP returns a Source that GETs url and decodes a JSON object of flag
+// names to booleans, skipping entries as File does. A nil client uses
+// http.DefaultClient; background refreshes are still bounded by the
+// RefreshTimeout option.
+func HTTP(client *http.Client, url string) Source {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return SourceFunc(func(ctx context.Context) (map[string]bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("featureflag: %s returned %s", url, resp.Status)
+		}
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("featureflag: reading %s: %w", url, err)
+		}
+		flags, err := decode(b)
+		if err != nil {
+			return nil, fmt.Errorf("featureflag: decoding %s: %w", url, err)
+		}
+		return flags, nil
+	})
+}
+
+// decode parses a JSON object of flag names to values. JSON booleans and
+// strings accepted by the Env source are recognized; any other value is
+// skipped, so like Env one bad entry does not fail the whole load. A
+// document that is not a JSON object is an error.
+func decode(data []byte) (map[string]bool, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	flags := make(map[string]bool, len(raw))
+	for name, v := range raw {
+		switch v := v.(type) {
+		case bool:
+			flags[name] = v
+		case string:
+			if b, ok := parseBool(v); ok {
+				flags[name] = b
+			}
+		}
+	}
+	return flags, nil
+}
+
+// Layered returns a Source merging sources in order, so later sources
+// override earlier ones. Any source error fails the whole load.
+func Layered(sources ...Source) Source {
+	return SourceFunc(func(ctx context.Context) (map[string]bool, error) {
+		flags := make(map[string]bool)
+		for _, src := range sources {
+			m, err := src.Load(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range m {
+				flags[normalize(k)] = v
+			}
+		}
+		return flags, nil
+	})
+}
+
+func normalize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '.':
+			return '_'
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// DefaultRefreshTimeout bounds a background refresh when no RefreshTimeout
+// option is given.
+const DefaultRefreshTimeout = 10 * time.Second
+
+// Option configures Flags.
+type Option func(*Flags)
+
+// RefreshTimeout bounds each background refresh of the source,
+// independently of the TTL. A value of zero or less uses
+// DefaultRefreshTimeout.
+func RefreshTimeout(d time.Duration) Option {
+	return func(f *Flags) { f.refreshTimeout = d }
+}
+
+// Flags serves flag values from the last successful load of its Source.
+type Flags struct {
+	src            Source
+	ttl            time.Duration
+	refreshTimeout time.Duration
+	defaults       map[string]bool
+
+	mu         sync.RWMutex
+	values     map[string]bool
+	loaded     time.Time
+	refreshing bool
+	lastErr    error
+}
+
+// New returns Flags backed by src. Values older than ttl are refreshed in
+// the background on the next lookup, and the stale values keep being
+// served until the refresh succeeds. A ttl of zero disables automatic
+// refresh. Each background refresh is bounded by the RefreshTimeout option,
+// whatever the ttl. defaults supplies the value of flags the source does
+// not set.
+//
+// New does not load src; call Refresh at startup to surface load errors.
+func New(src Source, ttl time.Duration, defaults map[string]bool, opts ...Option) *Flags {
+	d := make(map[string]bool, len(defaults))
+	for k, v := range defaults {
+		d[normalize(k)] = v
+	}
+	f := &Flags{src: src, ttl: ttl, refreshTimeout: DefaultRefreshTimeout, defaults: d}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.refreshTimeout <= 0 {
+		f.refreshTimeout = DefaultRefreshTimeout
+	}
+	return f
+}
+
+// Refresh loads src synchronously and replaces the current values. On
+// error the previous values are kept.
+func (f *Flags) Refresh(ctx context.Context) error {
+	m, err := f.src.Load(ctx)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastErr = err
+	if err != nil {
+		return err
+	}
+	values := make(map[string]bool, len(m))
+	for k, v := range m {
+		values[normalize(k)] = v
+	}
+	f.values = values
+	f.loaded = time.Now()
+	return nil
+}
+
+// Err returns the error from the most recent load, or nil.
+func (f *Flags) Err() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.lastErr
+}
+
+// Enabled reports whether the named flag is on.
+func (f *Flags) Enabled(name string) bool {
+	name = normalize(name)
+
+	f.mu.RLock()
+	v, ok := f.values[name]
+	stale := f.ttl > 0 && time.Since(f.loaded) >= f.ttl && !f.refreshing
+	f.mu.RUnlock()
+
+	if stale {
+		f.refreshAsync()
+	}
+	if ok {
+		return v
+	}
+	return f.defaults[name]
+}
+
+func (f *Flags) refreshAsync() {
+	f.mu.Lock()
+	if f.refreshing {
+		f.mu.Unlock()
+		return
+	}
+	f.refreshing = true
+	f.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), f.refreshTimeout)
+		defer cancel()
+		f.Refresh(ctx)
+		f.mu.Lock()
+		f.refreshing = false
+		if f.lastErr != nil {
+			// Back off for a full TTL rather than retrying on every lookup.
+			f.loaded = time.Now()
+		}
+		f.mu.Unlock()
+	}()
+}
+
+type contextKey struct{}
+
+type overrides struct {
+	parent *overrides
+	name   string
+	value  bool
+}
+
+type contextValue struct {
+	flags     *Flags
+	overrides *overrides
+}
+
+// NewContext returns a copy of ctx carrying f.
+func NewContext(ctx context.Context, f *Flags) context.Context {
+	cv, _ := ctx.Value(contextKey{}).(contextValue)
+	cv.flags = f
+	return context.WithValue(ctx, contextKey{}, cv)
+}
+
+// FromContext returns the Flags carried by ctx, or nil.
+func FromContext(ctx context.Context) *Flags {
+	cv, _ := ctx.Value(contextKey{}).(contextValue)
+	return cv.flags
+}
+
+// WithOverride returns a copy of ctx in which the named flag has value,
+// regardless of the Flags it carries. It is useful for per-request toggles
+// and tests.
+func WithOverride(ctx context.Context, name string, value bool) context.Context {
+	cv, _ := ctx.Value(contextKey{}).(contextValue)
+	cv.overrides = &overrides{parent: cv.overrides, name: normalize(name), value: value}
+	return context.WithValue(ctx, contextKey{}, cv)
+}
+
+// IsEnabled reports whether the named flag is on for ctx, honoring
+// WithOverride before the Flags carried by ctx. It returns false if ctx
+// carries neither.
+func IsEnabled(ctx context.Context, name string) bool {
+	cv, _ := ctx.Value(contextKey{}).(contextValue)
+	n := normalize(name)
+	for o := cv.overrides; o != nil; o = o.parent {
+		if o.name == n {
+			return o.value
+		}
+	}
+	if cv.flags == nil {
+		return false
+	}
+	return cv.flags.Enabled(name)
+}
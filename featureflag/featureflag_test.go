@@ -0,0 +1,439 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	if err := os.WriteFile(path, []byte(`{"new-collector":true,"enforce_signature":false}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := New(File(path), 0, nil)
+	if err := f.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Enabled("new_collector") || !f.Enabled("NEW.COLLECTOR") || f.Enabled("enforce-signature") {
+		t.Errorf("unexpected flag values %v", f.values)
+	}
+
+	if err := New(File(filepath.Join(t.TempDir(), "missing.json")), 0, nil).Refresh(context.Background()); err == nil {
+		t.Error("missing file: got nil error")
+	}
+	os.WriteFile(path, []byte(`{not json`), 0o644)
+	if err := New(File(path), 0, nil).Refresh(context.Background()); err == nil {
+		t.Error("invalid JSON: got nil error")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("FFTEST_NEW_COLLECTOR", "true")
+	t.Setenv("FFTEST_ENFORCE", "0")
+	t.Setenv("FFTEST_SHADOW_MODE", "Yes")
+	t.Setenv("FFTEST_LEGACY", "off")
+	t.Setenv("FFTEST_TYPO", "maybe")
+	t.Setenv("FFTEST_", "true")
+
+	flags, err := Env("FFTEST_").Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := map[string]bool{"NEW_COLLECTOR": true, "ENFORCE": false, "SHADOW_MODE": true, "LEGACY": false}
+	if len(flags) != len(want) {
+		t.Errorf("flags = %v, want %v", flags, want)
+	}
+	for k, v := range want {
+		if got, ok := flags[k]; !ok || got != v {
+			t.Errorf("%s = %v (present %v), want %v", k, got, ok, v)
+		}
+	}
+}
+
+func TestLayeredKeepsGoodValuesDespiteBadEnv(t *testing.T) {
+	t.Setenv("FFTEST_NEW_COLLECTOR", "false")
+	t.Setenv("FFTEST_BROKEN", "yes please")
+
+	file := SourceFunc(func(context.Context) (map[string]bool, error) {
+		return map[string]bool{"new-collector": true, "enforce_signature": true}, nil
+	})
+	f := New(Layered(file, Env("FFTEST_")), 0, nil)
+	if err := f.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if f.Enabled("new-collector") {
+		t.Error("env value did not override file value")
+	}
+	if !f.Enabled("enforce_signature") {
+		t.Error("file value lost because of an unparseable env variable")
+	}
+}
+
+func TestLayeredFailsOnSourceError(t *testing.T) {
+	bad := SourceFunc(func(context.Context) (map[string]bool, error) {
+		return nil, errors.New("unreachable")
+	})
+	if _, err := Layered(Env("FFTEST_"), bad).Load(context.Background()); err == nil {
+		t.Error("got nil error from failing layer")
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"remote":true}`))
+	}))
+	defer srv.Close()
+
+	flags, err := HTTP(srv.Client(), srv.URL).Load(context.Background())
+	if err != nil || !flags["remote"] {
+		t.Errorf("Load = %v, %v", flags, err)
+	}
+	status = http.StatusInternalServerError
+	if _, err := HTTP(srv.Client(), srv.URL).Load(context.Background()); err == nil {
+		t.Error("500 response: got nil error")
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	f := New(SourceFunc(func(context.Context) (map[string]bool, error) {
+		return map[string]bool{"a": false}, nil
+	}), 0, map[string]bool{"A": true, "b-flag": true})
+	if !f.Enabled("b_flag") {
+		t.Error("default not used before first load")
+	}
+	f.Refresh(context.Background())
+	if f.Enabled("a") {
+		t.Error("default overrode a loaded value")
+	}
+	if !f.Enabled("B.FLAG") || f.Enabled("missing") {
+		t.Error("wrong default after load")
+	}
+}
+
+func TestRefreshErrorKeepsValues(t *testing.T) {
+	fail := false
+	f := New(SourceFunc(func(context.Context) (map[string]bool, error) {
+		if fail {
+			return nil, errors.New("vault down")
+		}
+		return map[string]bool{"a": true}, nil
+	}), 0, nil)
+	f.Refresh(context.Background())
+	fail = true
+	if err := f.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh: got nil error")
+	}
+	if !f.Enabled("a") {
+		t.Error("failed refresh discarded previous values")
+	}
+	if f.Err() == nil {
+		t.Error("Err() = nil after failed refresh")
+	}
+}
+
+// fakeSource serves controllable values and counts loads. When gate is
+// non-nil each load blocks until a value is sent on it.
+type fakeSource struct {
+	mu    sync.Mutex
+	value bool
+	err   error
+	loads int32
+	gate  chan struct{}
+}
+
+func (s *fakeSource) Load(ctx context.Context) (map[string]bool, error) {
+	atomic.AddInt32(&s.loads, 1)
+	s.mu.Lock()
+	gate := s.gate
+	s.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return map[string]bool{"flag": s.value}, nil
+}
+
+func (s *fakeSource) set(value bool, err error, gate chan struct{}) {
+	s.mu.Lock()
+	s.value, s.err, s.gate = value, err, gate
+	s.mu.Unlock()
+}
+
+func (s *fakeSource) count() int32 {
+	return atomic.LoadInt32(&s.loads)
+}
+
+// waitIdle waits for any background refresh of f to finish.
+func waitIdle(t *testing.T, f *Flags) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		f.mu.RLock()
+		busy := f.refreshing
+		f.mu.RUnlock()
+		if !busy {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("background refresh did not finish")
+}
+
+const ttl = 50 * time.Millisecond
+
+func TestFreshValuesDoNotRefresh(t *testing.T) {
+	src := &fakeSource{value: true}
+	f := New(src, time.Hour, nil)
+	f.Refresh(context.Background())
+	for i := 0; i < 10; i++ {
+		f.Enabled("flag")
+	}
+	if got := src.count(); got != 1 {
+		t.Errorf("loads = %d, want 1", got)
+	}
+}
+
+func TestStaleValuesServedDuringRefresh(t *testing.T) {
+	src := &fakeSource{value: true}
+	f := New(src, ttl, nil)
+	f.Refresh(context.Background())
+	time.Sleep(ttl)
+
+	gate := make(chan struct{})
+	src.set(false, nil, gate)
+	if !f.Enabled("flag") {
+		t.Fatal("stale value not served while refresh is pending")
+	}
+	if !f.Enabled("flag") {
+		t.Fatal("stale value not served on second lookup")
+	}
+
+	close(gate)
+	waitIdle(t, f)
+	if f.Enabled("flag") {
+		t.Error("refreshed value not applied")
+	}
+}
+
+func TestSingleFlightRefresh(t *testing.T) {
+	src := &fakeSource{value: true}
+	f := New(src, ttl, nil)
+	f.Refresh(context.Background())
+	time.Sleep(ttl)
+
+	gate := make(chan struct{})
+	src.set(true, nil, gate)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.Enabled("flag")
+		}()
+	}
+	wg.Wait()
+	close(gate)
+	waitIdle(t, f)
+
+	if got := src.count(); got != 2 {
+		t.Errorf("loads = %d, want 2 (initial plus one shared refresh)", got)
+	}
+}
+
+func TestBackoffAfterRefreshError(t *testing.T) {
+	src := &fakeSource{value: true}
+	f := New(src, ttl, nil)
+	f.Refresh(context.Background())
+	time.Sleep(ttl)
+
+	src.set(false, errors.New("down"), nil)
+	f.Enabled("flag")
+	waitIdle(t, f)
+	if got := src.count(); got != 2 {
+		t.Fatalf("loads = %d, want 2", got)
+	}
+	if f.Err() == nil {
+		t.Fatal("Err() = nil after failed background refresh")
+	}
+
+	// Lookups right after a failure must not retry on every call.
+	for i := 0; i < 10; i++ {
+		if !f.Enabled("flag") {
+			t.Fatal("last good value not served after failed refresh")
+		}
+	}
+	waitIdle(t, f)
+	if got := src.count(); got != 2 {
+		t.Errorf("loads = %d during back-off, want 2", got)
+	}
+
+	// Once the back-off TTL has passed, the next lookup retries.
+	src.set(false, nil, nil)
+	time.Sleep(ttl)
+	f.Enabled("flag")
+	waitIdle(t, f)
+	if got := src.count(); got != 3 {
+		t.Errorf("loads = %d after back-off, want 3", got)
+	}
+	if f.Enabled("flag") || f.Err() != nil {
+		t.Error("recovered value not applied")
+	}
+}
+
+func TestZeroTTLNeverRefreshes(t *testing.T) {
+	src := &fakeSource{value: true}
+	f := New(src, 0, nil)
+	f.Refresh(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	f.Enabled("flag")
+	waitIdle(t, f)
+	if got := src.count(); got != 1 {
+		t.Errorf("loads = %d, want 1", got)
+	}
+}
+
+func TestContextHelpers(t *testing.T) {
+	f := New(SourceFunc(func(context.Context) (map[string]bool, error) {
+		return map[string]bool{"a": true, "b": false}, nil
+	}), 0, nil)
+	f.Refresh(context.Background())
+
+	if IsEnabled(context.Background(), "a") {
+		t.Error("IsEnabled true on empty context")
+	}
+	if FromContext(context.Background()) != nil {
+		t.Error("FromContext non-nil on empty context")
+	}
+
+	ctx := NewContext(context.Background(), f)
+	if FromContext(ctx) != f {
+		t.Error("FromContext did not return stored Flags")
+	}
+	if !IsEnabled(ctx, "a") || IsEnabled(ctx, "b") {
+		t.Error("IsEnabled did not consult stored Flags")
+	}
+
+	ctx = WithOverride(ctx, "A", false)
+	ctx = WithOverride(ctx, "b", true)
+	if IsEnabled(ctx, "a") || !IsEnabled(ctx, "B") {
+		t.Error("overrides not honored")
+	}
+	if inner := WithOverride(ctx, "a", true); !IsEnabled(inner, "a") || IsEnabled(ctx, "a") {
+		t.Error("newest override should win without affecting parent context")
+	}
+
+	// Overrides set before NewContext survive it.
+	ctx = NewContext(WithOverride(context.Background(), "b", true), f)
+	if !IsEnabled(ctx, "b") {
+		t.Error("override lost by NewContext")
+	}
+}
+
+func TestFileSkipsNonBoolValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	doc := `{"on":true,"off":false,"yes":"yes","str_false":"false","num":1,"typo":"maybe","nested":{"a":true},"null":null,"list":[true]}`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	flags, err := File(path).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := map[string]bool{"on": true, "off": false, "yes": true, "str_false": false}
+	if !reflect.DeepEqual(flags, want) {
+		t.Errorf("flags = %v, want %v", flags, want)
+	}
+}
+
+func TestHTTPSkipsNonBoolValues(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"remote":true,"broken":"sure","count":3}`))
+	}))
+	defer srv.Close()
+
+	flags, err := HTTP(srv.Client(), srv.URL).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if want := map[string]bool{"remote": true}; !reflect.DeepEqual(flags, want) {
+		t.Errorf("flags = %v, want %v", flags, want)
+	}
+}
+
+func TestLayeredKeepsGoodValuesDespiteBadFileEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	os.WriteFile(path, []byte(`{"enforce_signature":true,"new_collector":"tru"}`), 0o644)
+	remote := SourceFunc(func(context.Context) (map[string]bool, error) {
+		return map[string]bool{"shadow_mode": true}, nil
+	})
+	f := New(Layered(File(path), remote), 0, map[string]bool{"new_collector": true})
+	if err := f.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if !f.Enabled("enforce_signature") || !f.Enabled("shadow_mode") {
+		t.Error("good values lost because of one bad file entry")
+	}
+	if !f.Enabled("new_collector") {
+		t.Error("skipped entry should fall back to its default")
+	}
+}
+
+func TestRefreshTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want time.Duration
+	}{
+		{"default", nil, DefaultRefreshTimeout},
+		{"option", []Option{RefreshTimeout(200 * time.Millisecond)}, 200 * time.Millisecond},
+		{"non-positive option", []Option{RefreshTimeout(0)}, DefaultRefreshTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var deadline time.Time
+			loads := 0
+			src := SourceFunc(func(ctx context.Context) (map[string]bool, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				loads++
+				deadline, _ = ctx.Deadline()
+				return map[string]bool{"a": true}, nil
+			})
+			f := New(src, 10*time.Millisecond, nil, tt.opts...)
+			f.Refresh(context.Background())
+			time.Sleep(10 * time.Millisecond)
+			f.Enabled("a")
+			waitIdle(t, f)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if loads != 2 {
+				t.Fatalf("loads = %d, want 2", loads)
+			}
+			// The refresh must get its own budget, not the 10ms TTL.
+			if left := time.Until(deadline); left <= tt.want-100*time.Millisecond || left > tt.want {
+				t.Errorf("refresh deadline in %v, want about %v", left, tt.want)
+			}
+		})
+	}
+
+	if f := New(&fakeSource{}, time.Hour, nil); f.refreshTimeout != DefaultRefreshTimeout {
+		t.Errorf("1h TTL refresh timeout = %v, want %v", f.refreshTimeout, DefaultRefreshTimeout)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/adcosta-hbo/snowflake-monitor
+
+go 1.21
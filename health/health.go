@@ -0,0 +1,322 @@
+// Package health provides a registry of named health checks and HTTP
+// handlers that aggregate them into liveness and readiness endpoints.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a single check when no Timeout option is given.
+const DefaultTimeout = 5 * time.Second
+
+// Status values reported by checks and by the aggregate report.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Checker reports whether a dependency is healthy. Check should return
+// promptly once ctx is done.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts an ordinary function to the Checker interface.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Pinger is satisfied by *sql.DB and other clients exposing PingContext.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Ping returns a Checker that pings p, e.g. a Snowflake *sql.DB.
+func Ping(p Pinger) Checker {
+	return CheckerFunc(p.PingContext)
+}
+
+// maxDrain bounds how much of a probe response body is read to allow
+// connection reuse.
+const maxDrain = 1 << 20
+
+// HTTPGet returns a Checker that issues a GET to url and fails on transport
+// errors or a 5xx status. A nil client uses http.DefaultClient.
+func HTTPGet(client *http.Client, url string) Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return CheckerFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		// Drain the body so the connection can be reused by the next probe.
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrain))
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return errors.New("health: " + url + " returned " + resp.Status)
+		}
+		return nil
+	})
+}
+
+// Option configures a registered check.
+type Option func(*check)
+
+// Timeout bounds how long a single run of the check may take. A value of
+// zero or less uses DefaultTimeout.
+func Timeout(d time.Duration) Option {
+	return func(c *check) { c.timeout = d }
+}
+
+// CacheFor reuses the last result for d instead of running the check on
+// every request, which protects expensive dependencies from probe traffic.
+func CacheFor(d time.Duration) Option {
+	return func(c *check) { c.ttl = d }
+}
+
+// Liveness includes the check in the liveness endpoint as well as the
+// readiness endpoint. Only checks whose failure warrants a restart should
+// use it.
+func Liveness() Option {
+	return func(c *check) { c.liveness = true }
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"-"`
+	checked  time.Time
+}
+
+// MarshalJSON reports Duration in milliseconds.
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	out := struct {
+		result
+		Duration float64 `json:"durationMs"`
+	}{result(r), float64(r.Duration) / float64(time.Millisecond)}
+	return json.Marshal(out)
+}
+
+// Report is the aggregate outcome of a set of checks.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// OK reports whether every check in the report passed.
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type check struct {
+	name     string
+	checker  Checker
+	timeout  time.Duration
+	ttl      time.Duration
+	liveness bool
+
+	mu       sync.Mutex
+	last     *Result
+	inflight *call
+}
+
+// call is a single in-flight run of a check shared by concurrent callers.
+type call struct {
+	done chan struct{}
+	once sync.Once
+	res  Result
+}
+
+// run returns the cached result if fresh, otherwise joins or starts a run
+// of the checker. The run is detached from ctx's cancellation and bounded
+// only by the check's timeout, so an aborted probe neither cuts the check
+// short nor leaves a failure in the cache. A caller whose ctx ends first
+// gets an uncached failure.
+func (c *check) run(ctx context.Context) Result {
+	c.mu.Lock()
+	if c.last != nil && c.ttl > 0 && time.Since(c.last.checked) < c.ttl {
+		res := *c.last
+		c.mu.Unlock()
+		return res
+	}
+	cl := c.inflight
+	if cl == nil {
+		cl = &call{done: make(chan struct{})}
+		c.inflight = cl
+		go c.execute(context.WithoutCancel(ctx), cl)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.res
+	case <-ctx.Done():
+		return Result{Status: StatusFail, Error: ctx.Err().Error(), checked: time.Now()}
+	}
+}
+
+// execute runs the checker for cl. Waiters are released with the result as
+// soon as the checker returns or the timeout passes, whichever is first,
+// but cl stays in flight until the checker actually returns. A checker
+// that ignores its context therefore ties up a single goroutine rather
+// than one per probe.
+func (c *check) execute(ctx context.Context, cl *call) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	publish := func(err error) {
+		cl.once.Do(func() {
+			res := Result{Status: StatusOK, Duration: time.Since(start), checked: time.Now()}
+			if err != nil {
+				res.Status = StatusFail
+				res.Error = err.Error()
+			}
+			c.mu.Lock()
+			cl.res = res
+			c.last = &res
+			c.mu.Unlock()
+			close(cl.done)
+		})
+	}
+
+	checked := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			publish(ctx.Err())
+		case <-checked:
+		}
+	}()
+
+	err := c.checker.Check(ctx)
+	close(checked)
+	publish(err)
+
+	c.mu.Lock()
+	c.inflight = nil
+	c.mu.Unlock()
+}
+
+// Registry holds named checks. The zero value is not usable; use
+// NewRegistry.
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]*check
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]*check)}
+}
+
+// Register adds c under name, replacing any check already registered with
+// that name.
+func (r *Registry) Register(name string, c Checker, opts ...Option) {
+	chk := &check{name: name, checker: c, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(chk)
+	}
+	if chk.timeout <= 0 {
+		chk.timeout = DefaultTimeout
+	}
+	r.mu.Lock()
+	r.checks[name] = chk
+	r.mu.Unlock()
+}
+
+// Unregister removes the check registered under name, if any.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.checks, name)
+	r.mu.Unlock()
+}
+
+// Ready runs every registered check concurrently and returns the aggregate
+// report.
+func (r *Registry) Ready(ctx context.Context) Report {
+	return r.run(ctx, false)
+}
+
+// Live runs the checks registered with the Liveness option and returns the
+// aggregate report. With no such checks the report is always OK.
+func (r *Registry) Live(ctx context.Context) Report {
+	return r.run(ctx, true)
+}
+
+func (r *Registry) run(ctx context.Context, livenessOnly bool) Report {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		if !livenessOnly || c.liveness {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// HealthzHandler serves the liveness report, responding 503 if any liveness
+// check fails.
+func (r *Registry) HealthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, r.Live(req.Context()))
+	})
+}
+
+// ReadyzHandler serves the readiness report, responding 503 if any check
+// fails.
+func (r *Registry) ReadyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, r.Ready(req.Context()))
+	})
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
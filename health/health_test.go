@@ -0,0 +1,289 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadyAggregatesChecks(t *testing.T) {
+	r := NewRegistry()
+	r.Register("ok", CheckerFunc(func(context.Context) error { return nil }))
+	r.Register("bad", CheckerFunc(func(context.Context) error { return errors.New("boom") }))
+
+	report := r.Ready(context.Background())
+	if report.OK() {
+		t.Fatal("report OK with a failing check")
+	}
+	if got := report.Checks["ok"].Status; got != StatusOK {
+		t.Errorf("ok check status = %q, want %q", got, StatusOK)
+	}
+	if got := report.Checks["bad"]; got.Status != StatusFail || got.Error != "boom" {
+		t.Errorf("bad check = %+v, want fail with error boom", got)
+	}
+
+	r.Unregister("bad")
+	if report := r.Ready(context.Background()); !report.OK() {
+		t.Errorf("report not OK after removing failing check: %+v", report)
+	}
+}
+
+func TestLiveOnlyRunsLivenessChecks(t *testing.T) {
+	r := NewRegistry()
+	r.Register("db", CheckerFunc(func(context.Context) error { return errors.New("down") }))
+	if report := r.Live(context.Background()); !report.OK() || len(report.Checks) != 0 {
+		t.Errorf("Live with no liveness checks = %+v, want empty OK report", report)
+	}
+
+	r.Register("deadlock", CheckerFunc(func(context.Context) error { return errors.New("stuck") }), Liveness())
+	report := r.Live(context.Background())
+	if report.OK() || len(report.Checks) != 1 {
+		t.Errorf("Live = %+v, want only the failing liveness check", report)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	r := NewRegistry()
+	r.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}), Timeout(10*time.Millisecond))
+
+	res := r.Ready(context.Background()).Checks["slow"]
+	if res.Status != StatusFail || res.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("slow check = %+v, want deadline exceeded", res)
+	}
+}
+
+func TestNonPositiveTimeoutUsesDefault(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		r := NewRegistry()
+		r.Register("ok", CheckerFunc(func(context.Context) error { return nil }), Timeout(d))
+		if report := r.Ready(context.Background()); !report.OK() {
+			t.Errorf("Timeout(%v): report = %+v, want OK", d, report)
+		}
+		if got := r.checks["ok"].timeout; got != DefaultTimeout {
+			t.Errorf("Timeout(%v): timeout = %v, want %v", d, got, DefaultTimeout)
+		}
+	}
+}
+
+func TestCacheFor(t *testing.T) {
+	var runs int32
+	r := NewRegistry()
+	r.Register("db", CheckerFunc(func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}), CacheFor(time.Hour))
+
+	for i := 0; i < 3; i++ {
+		r.Ready(context.Background())
+	}
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("checker ran %d times, want 1", got)
+	}
+}
+
+func TestCancelledCallerDoesNotPoisonCache(t *testing.T) {
+	var runs int32
+	release := make(chan struct{})
+	r := NewRegistry()
+	r.Register("db", CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}), CacheFor(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := r.Ready(ctx).Checks["db"]
+	if res.Status != StatusFail || res.Error != context.Canceled.Error() {
+		t.Fatalf("cancelled caller got %+v, want fail with context canceled", res)
+	}
+
+	close(release)
+	if report := r.Ready(context.Background()); !report.OK() {
+		t.Errorf("healthy probe after cancelled probe = %+v, want OK", report)
+	}
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("checker ran %d times, want 1 shared run", got)
+	}
+}
+
+func TestConcurrentProbesShareRun(t *testing.T) {
+	var runs int32
+	release := make(chan struct{})
+	r := NewRegistry()
+	r.Register("db", CheckerFunc(func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return nil
+	}))
+
+	var wg sync.WaitGroup
+	reports := make([]Report, 5)
+	for i := range reports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reports[i] = r.Ready(context.Background())
+		}(i)
+	}
+	// Let every caller join the in-flight run before it completes.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("checker ran %d times, want 1", got)
+	}
+	for i, report := range reports {
+		if !report.OK() {
+			t.Errorf("report %d = %+v, want OK", i, report)
+		}
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	r := NewRegistry()
+	r.Register("live", CheckerFunc(func(context.Context) error { return nil }), Liveness())
+	r.Register("db", CheckerFunc(func(context.Context) error { return errors.New("down") }))
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		status  int
+		checks  int
+	}{
+		{"healthz", r.HealthzHandler(), http.StatusOK, 1},
+		{"readyz", r.ReadyzHandler(), http.StatusServiceUnavailable, 2},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tt.name, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: Content-Type = %q", tt.name, ct)
+		}
+		var body struct {
+			Status string                     `json:"status"`
+			Checks map[string]json.RawMessage `json:"checks"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decoding body: %v", tt.name, err)
+		}
+		if len(body.Checks) != tt.checks {
+			t.Errorf("%s: %d checks in body, want %d", tt.name, len(body.Checks), tt.checks)
+		}
+	}
+}
+
+func TestPing(t *testing.T) {
+	want := errors.New("no connection")
+	if err := Ping(pinger{want}).Check(context.Background()); err != want {
+		t.Errorf("Ping check = %v, want %v", err, want)
+	}
+}
+
+type pinger struct{ err error }
+
+func (p pinger) PingContext(context.Context) error { return p.err }
+
+func TestHTTPGet(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c := HTTPGet(srv.Client(), srv.URL)
+	if err := c.Check(context.Background()); err != nil {
+		t.Errorf("200 response: %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if err := c.Check(context.Background()); err == nil {
+		t.Error("503 response: got nil error")
+	}
+}
+
+func TestHungCheckerStaysInFlight(t *testing.T) {
+	var runs int32
+	release := make(chan struct{})
+	r := NewRegistry()
+	r.Register("driver", CheckerFunc(func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		<-release // ignores its context, like a hung driver
+		return nil
+	}), Timeout(10*time.Millisecond))
+
+	for i := 0; i < 5; i++ {
+		res := r.Ready(context.Background()).Checks["driver"]
+		if res.Status != StatusFail || res.Error != context.DeadlineExceeded.Error() {
+			t.Fatalf("probe %d = %+v, want deadline exceeded", i, res)
+		}
+	}
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("hung checker started %d times, want 1", got)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		r.checks["driver"].mu.Lock()
+		idle := r.checks["driver"].inflight == nil
+		r.checks["driver"].mu.Unlock()
+		if idle {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("check still in flight after checker returned")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if report := r.Ready(context.Background()); !report.OK() {
+		t.Errorf("probe after checker recovered = %+v, want OK", report)
+	}
+	if got := atomic.LoadInt32(&runs); got != 2 {
+		t.Errorf("checker ran %d times, want 2", got)
+	}
+}
+
+func TestHTTPGetReusesConnection(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Large enough that closing the body unread would discard the
+		// connection, even with the transport's own small-body draining.
+		w.Write(bytes.Repeat([]byte("x"), 512<<10))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	c := HTTPGet(srv.Client(), srv.URL)
+	for i := 0; i < 3; i++ {
+		if err := c.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&conns); got != 1 {
+		t.Errorf("probes opened %d connections, want 1", got)
+	}
+}
@@ -0,0 +1,158 @@
+// Package lifecycle coordinates shutdown of a service's components so that
+// buffered logs, metrics and spans are flushed before the process exits.
+//
+// Components register hooks as they start; on shutdown the hooks run one
+// at a time in reverse registration order, so a component is stopped
+// before the things it depends on.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultHookTimeout bounds a single hook when no timeout is given to
+// Register.
+const DefaultHookTimeout = 5 * time.Second
+
+// DefaultShutdownTimeout bounds the whole shutdown started by
+// WaitForSignal when no timeout is given.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Hook stops or flushes a component. It should return promptly once ctx is
+// done.
+type Hook func(ctx context.Context) error
+
+// ErrShutdownStarted is returned by Register when shutdown has already
+// begun.
+var ErrShutdownStarted = errors.New("lifecycle: shutdown already started")
+
+// Closer adapts a Close method such as io.Closer's to a Hook.
+func Closer(fn func() error) Hook {
+	return func(context.Context) error { return fn() }
+}
+
+type hook struct {
+	name    string
+	fn      Hook
+	timeout time.Duration
+}
+
+// Manager holds shutdown hooks. The zero value is ready to use.
+type Manager struct {
+	mu      sync.Mutex
+	hooks   []hook
+	started bool
+	once    sync.Once
+	err     error
+}
+
+// Register adds a hook run on shutdown under name. A timeout of zero or
+// less uses DefaultHookTimeout.
+//
+// A component registering after Shutdown has started would never be
+// stopped, so its hook is instead run immediately, under its own timeout,
+// and Register returns ErrShutdownStarted joined with any error from the
+// hook.
+func (m *Manager) Register(name string, fn Hook, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	h := hook{name: name, fn: fn, timeout: timeout}
+
+	m.mu.Lock()
+	if !m.started {
+		m.hooks = append(m.hooks, h)
+		m.mu.Unlock()
+		return nil
+	}
+	m.mu.Unlock()
+
+	err := ErrShutdownStarted
+	if herr := run(context.Background(), h); herr != nil {
+		err = errors.Join(err, fmt.Errorf("lifecycle: %s: %w", name, herr))
+	}
+	return err
+}
+
+// Shutdown runs the registered hooks in reverse order, each bounded by its
+// own timeout and by ctx. A failing or timed-out hook does not stop later
+// hooks from running. The returned error joins every hook failure.
+//
+// Only the first call runs the hooks; later calls return the same result.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.once.Do(func() {
+		m.mu.Lock()
+		m.started = true
+		hooks := make([]hook, len(m.hooks))
+		copy(hooks, m.hooks)
+		m.mu.Unlock()
+
+		var errs []error
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if err := run(ctx, hooks[i]); err != nil {
+				errs = append(errs, fmt.Errorf("lifecycle: %s: %w", hooks[i].name, err))
+			}
+		}
+		m.err = errors.Join(errs...)
+	})
+	return m.err
+}
+
+func run(ctx context.Context, h hook) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() { errc <- h.fn(ctx) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitForSignal blocks until the process receives one of sigs (SIGINT and
+// SIGTERM when none are given) or ctx is done, then calls Shutdown with a
+// context bounded by timeout. A timeout of zero or less uses
+// DefaultShutdownTimeout.
+func (m *Manager) WaitForSignal(ctx context.Context, timeout time.Duration, sigs ...os.Signal) error {
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	ctx, stop := signal.NotifyContext(ctx, sigs...)
+	<-ctx.Done()
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	return m.Shutdown(shutdownCtx)
+}
+
+var std Manager
+
+// Register adds a hook to the package-level Manager.
+func Register(name string, fn Hook, timeout time.Duration) error {
+	return std.Register(name, fn, timeout)
+}
+
+// Shutdown runs the hooks of the package-level Manager.
+func Shutdown(ctx context.Context) error {
+	return std.Shutdown(ctx)
+}
+
+// WaitForSignal waits for a signal and shuts down the package-level
+// Manager.
+func WaitForSignal(ctx context.Context, timeout time.Duration, sigs ...os.Signal) error {
+	return std.WaitForSignal(ctx, timeout, sigs...)
+}
@@ -0,0 +1,212 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) hook(name string, err error) Hook {
+	return func(context.Context) error {
+		r.mu.Lock()
+		r.calls = append(r.calls, name)
+		r.mu.Unlock()
+		return err
+	}
+}
+
+func (r *recorder) got() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func TestShutdownRunsHooksInReverseOrder(t *testing.T) {
+	var m Manager
+	var rec recorder
+	for _, name := range []string{"llog", "metrics", "tracer", "scheduler"} {
+		if err := m.Register(name, rec.hook(name, nil), 0); err != nil {
+			t.Fatalf("Register(%s): %v", name, err)
+		}
+	}
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got, want := rec.got(), []string{"scheduler", "tracer", "metrics", "llog"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hook order = %v, want %v", got, want)
+	}
+}
+
+func TestShutdownJoinsErrorsAndContinues(t *testing.T) {
+	var m Manager
+	var rec recorder
+	errMetrics := errors.New("statsd unreachable")
+	errTracer := errors.New("reporter closed")
+	m.Register("llog", rec.hook("llog", nil), 0)
+	m.Register("metrics", rec.hook("metrics", errMetrics), 0)
+	m.Register("tracer", Closer(func() error { rec.hook("tracer", nil)(context.Background()); return errTracer }), 0)
+
+	err := m.Shutdown(context.Background())
+	if !errors.Is(err, errMetrics) || !errors.Is(err, errTracer) {
+		t.Errorf("Shutdown error %v does not wrap both hook errors", err)
+	}
+	if got, want := rec.got(), []string{"tracer", "metrics", "llog"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hook order = %v, want every hook despite errors: %v", got, want)
+	}
+}
+
+func TestPerHookTimeout(t *testing.T) {
+	var m Manager
+	var rec recorder
+	m.Register("fast", rec.hook("fast", nil), time.Second)
+	m.Register("stuck", func(context.Context) error {
+		select {} // ignores its context entirely
+	}, 20*time.Millisecond)
+
+	start := time.Now()
+	err := m.Shutdown(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Shutdown took %v, want it bounded by the stuck hook's timeout", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown error = %v, want deadline exceeded", err)
+	}
+	if got := rec.got(); !reflect.DeepEqual(got, []string{"fast"}) {
+		t.Errorf("hooks after timeout = %v, want [fast]", got)
+	}
+}
+
+func TestHookSeesDeadline(t *testing.T) {
+	var m Manager
+	var deadline time.Time
+	m.Register("flush", func(ctx context.Context) error {
+		deadline, _ = ctx.Deadline()
+		return nil
+	}, 0)
+	m.Shutdown(context.Background())
+	if left := time.Until(deadline); left <= 0 || left > DefaultHookTimeout {
+		t.Errorf("hook deadline in %v, want within DefaultHookTimeout", left)
+	}
+}
+
+func TestShutdownRunsOnce(t *testing.T) {
+	var m Manager
+	var rec recorder
+	want := errors.New("boom")
+	m.Register("a", rec.hook("a", want), 0)
+
+	first := m.Shutdown(context.Background())
+	second := m.Shutdown(context.Background())
+	if !errors.Is(first, want) || first != second {
+		t.Errorf("Shutdown results %v, %v, want the same error twice", first, second)
+	}
+	if got := rec.got(); len(got) != 1 {
+		t.Errorf("hook ran %d times, want 1", len(got))
+	}
+}
+
+func TestRegisterAfterShutdownRunsHookImmediately(t *testing.T) {
+	var m Manager
+	var rec recorder
+	m.Shutdown(context.Background())
+
+	if err := m.Register("late", rec.hook("late", nil), 0); !errors.Is(err, ErrShutdownStarted) {
+		t.Errorf("Register after shutdown = %v, want ErrShutdownStarted", err)
+	}
+	if got := rec.got(); !reflect.DeepEqual(got, []string{"late"}) {
+		t.Errorf("late hook calls = %v, want [late]", got)
+	}
+
+	hookErr := errors.New("flush failed")
+	err := m.Register("late-fail", rec.hook("late-fail", hookErr), 0)
+	if !errors.Is(err, ErrShutdownStarted) || !errors.Is(err, hookErr) {
+		t.Errorf("Register error = %v, want ErrShutdownStarted and the hook error", err)
+	}
+}
+
+func TestWaitForSignalOnContextDone(t *testing.T) {
+	var m Manager
+	var rec recorder
+	m.Register("a", rec.hook("a", nil), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.WaitForSignal(ctx, time.Second); err != nil {
+		t.Fatalf("WaitForSignal: %v", err)
+	}
+	if got := rec.got(); len(got) != 1 {
+		t.Errorf("hooks run = %v, want [a]", got)
+	}
+}
+
+func TestWaitForSignalNonPositiveTimeoutUsesDefault(t *testing.T) {
+	for _, timeout := range []time.Duration{0, -time.Second} {
+		var m Manager
+		var deadline time.Time
+		called := false
+		m.Register("flush", func(ctx context.Context) error {
+			called = true
+			deadline, _ = ctx.Deadline()
+			return ctx.Err()
+		}, time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := m.WaitForSignal(ctx, timeout); err != nil {
+			t.Errorf("WaitForSignal(timeout %v) = %v, want nil", timeout, err)
+		}
+		if !called {
+			t.Errorf("timeout %v: flush hook did not run", timeout)
+		}
+		if left := time.Until(deadline); left <= 0 || left > DefaultShutdownTimeout {
+			t.Errorf("timeout %v: hook deadline in %v, want within DefaultShutdownTimeout", timeout, left)
+		}
+	}
+}
+
+func TestWaitForSignal(t *testing.T) {
+	var m Manager
+	var rec recorder
+	m.Register("a", rec.hook("a", nil), 0)
+
+	// Hold our own subscription so a SIGUSR1 sent before WaitForSignal
+	// subscribes cannot take the default action and kill the test binary.
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGUSR1)
+	defer signal.Stop(guard)
+
+	done := make(chan error, 1)
+	go func() { done <- m.WaitForSignal(context.Background(), time.Second, syscall.SIGUSR1) }()
+
+	// Keep signalling until the handler is installed and shutdown runs.
+	tick := time.NewTicker(5 * time.Millisecond)
+	defer tick.Stop()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("WaitForSignal: %v", err)
+			}
+			if got := rec.got(); len(got) != 1 {
+				t.Errorf("hooks run = %v, want [a]", got)
+			}
+			return
+		case <-tick.C:
+			syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+		case <-timeout:
+			t.Fatal("WaitForSignal did not return after SIGUSR1")
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package naming converts identifiers between case conventions and into
+// valid Prometheus metric and label names, e.g. when turning Snowflake
+// column names into exported metrics.
+package naming
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// words splits s into lower-cased words. ASCII punctuation and symbols,
+// and any space, punctuation or control rune, separate words, as does a
+// case change: "userID" gives
+// "user", "id" and "HTTPServer" gives "http", "server". Digits stay with
+// the word they follow, so "http2Server" gives "http2", "server".
+func words(s string) []string {
+	runes := []rune(s)
+	var out []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, string(cur))
+			cur = cur[:0]
+		}
+	}
+	for i, r := range runes {
+		if !isWordRune(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(cur) > 0 {
+			// Combining marks take the case of the rune they modify.
+			j := i - 1
+			for j > 0 && unicode.IsMark(runes[j]) {
+				j--
+			}
+			prev := runes[j]
+			switch {
+			case unicode.IsLower(prev) || unicode.IsDigit(prev):
+				flush()
+			case unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+				// Last capital of an acronym starts the next word.
+				flush()
+			}
+		}
+		cur = append(cur, unicode.ToLower(r))
+	}
+	flush()
+	return out
+}
+
+// isWordRune reports whether r belongs in a word: letters, digits, and any
+// other non-ASCII rune such as a combining mark or an emoji that is not a
+// space, punctuation or control character.
+func isWordRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return true
+	}
+	return r >= utf8.RuneSelf && r != utf8.RuneError &&
+		!unicode.IsSpace(r) && !unicode.IsPunct(r) && !unicode.IsControl(r)
+}
+
+// ToSnakeCase converts s to lower snake_case, e.g. "BytesScanned",
+// "bytes-scanned" and "BYTES_SCANNED" all become "bytes_scanned".
+func ToSnakeCase(s string) string {
+	return strings.Join(words(s), "_")
+}
+
+// ToCamelCase converts s to lowerCamelCase, e.g. "BYTES_SCANNED" becomes
+// "bytesScanned". Acronyms are treated as ordinary words, so "HTTP_SERVER"
+// becomes "httpServer".
+func ToCamelCase(s string) string {
+	var b strings.Builder
+	for i, w := range words(s) {
+		if i == 0 {
+			b.WriteString(w)
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
+
+// SanitizeMetricName converts s to a snake_case name valid as both a
+// Prometheus metric name and a label name. ASCII letters, digits and
+// underscores are kept. Any other rune is escaped as "U" and four
+// upper-case hex digits, or "V" and six for runes beyond U+FFFF, so
+// "größe" becomes "grU00F6U00DFe". A name starting with a digit is
+// prefixed with an underscore and an input with nothing usable gives "_".
+//
+// Snake-cased names never contain upper-case ASCII, so the escaping is
+// unambiguous: distinct ToSnakeCase results always give distinct names,
+// and columns differing only in non-ASCII letters do not collide.
+func SanitizeMetricName(s string) string {
+	snake := ToSnakeCase(s)
+	if snake == "" {
+		return "_"
+	}
+	var b strings.Builder
+	if snake[0] >= '0' && snake[0] <= '9' {
+		b.WriteByte('_')
+	}
+	for _, r := range snake {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_':
+			b.WriteRune(r)
+		case r <= 0xFFFF:
+			fmt.Fprintf(&b, "U%04X", r)
+		default:
+			fmt.Fprintf(&b, "V%06X", r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package naming
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestToSnakeCase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"bytes", "bytes"},
+		{"BytesScanned", "bytes_scanned"},
+		{"bytesScanned", "bytes_scanned"},
+		{"BYTES_SCANNED", "bytes_scanned"},
+		{"bytes-scanned.total", "bytes_scanned_total"},
+		{"  query  id  ", "query_id"},
+		{"__query__id__", "query_id"},
+		{"userID", "user_id"},
+		{"HTTPServer", "http_server"},
+		{"QueryIDHash", "query_id_hash"},
+		{"http2Server", "http2_server"},
+		{"p99Latency", "p99_latency"},
+		{"2xxCount", "2xx_count"},
+		{"A1B2", "a1_b2"},
+		{"ÜberName", "über_name"},
+		{"größeGesamt", "größe_gesamt"},
+		{"日本語", "日本語"},
+		{"cafe\u0301Count", "cafe\u0301_count"},
+		{"😀count", "😀count"},
+		{"a 😀 b", "a_😀_b"},
+		{"“quoted”", "quoted"},
+	}
+	for _, tt := range tests {
+		if got := ToSnakeCase(tt.in); got != tt.want {
+			t.Errorf("ToSnakeCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToCamelCase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"bytes", "bytes"},
+		{"BYTES_SCANNED", "bytesScanned"},
+		{"bytes_scanned", "bytesScanned"},
+		{"BytesScanned", "bytesScanned"},
+		{"HTTP_SERVER", "httpServer"},
+		{"user_id", "userId"},
+		{"http2_server", "http2Server"},
+		{"p99_latency_ms", "p99LatencyMs"},
+		{"über_name", "überName"},
+	}
+	for _, tt := range tests {
+		if got := ToCamelCase(tt.in); got != tt.want {
+			t.Errorf("ToCamelCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeMetricName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "_"},
+		{"---", "_"},
+		{"BYTES_SCANNED", "bytes_scanned"},
+		{"CreditsUsed", "credits_used"},
+		{"warehouse:load", "warehouse_load"},
+		{"avg (ms)", "avg_ms"},
+		{"95thPercentile", "_95th_percentile"},
+		{"2xx", "_2xx"},
+		{"__reserved", "reserved"},
+		{"größe", "grU00F6U00DFe"},
+		{"😀count", "V01F600count"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeMetricName(tt.in); got != tt.want {
+			t.Errorf("SanitizeMetricName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Inputs whose snake_case forms differ only in non-ASCII runes, or in
+// runes an ASCII-only mapping would drop.
+var collisionCandidates = []string{
+	"ÜberName", "berName", "UberName",
+	"Éclair", "clair", "Eclair",
+	"größeGesamt", "groesseGesamt", "grGesamt", "gr_e_gesamt",
+	"日本語", "中文", "한국어", "売上", "_",
+	"naïve", "naive", "nave",
+	"😀", "😁", "U1F600", "u01f600",
+	"٣count", "3count", "count",
+	"ßeta", "eta",
+}
+
+func TestSanitizeMetricNameNoCollisions(t *testing.T) {
+	seen := make(map[string]string)
+	for _, in := range collisionCandidates {
+		got := SanitizeMetricName(in)
+		if prev, ok := seen[got]; ok {
+			t.Errorf("SanitizeMetricName(%q) and SanitizeMetricName(%q) both give %q", prev, in, got)
+		}
+		seen[got] = in
+	}
+}
+
+var validName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func TestSanitizeMetricNameRoundTrip(t *testing.T) {
+	inputs := append([]string{
+		"", "BYTES_SCANNED", "HTTPServer", "95thPercentile", "p99Latency",
+		"Ünïcödé Column", "QUERY-ID.hash", "\xff\xfeinvalid",
+	}, collisionCandidates...)
+	for _, in := range inputs {
+		got := SanitizeMetricName(in)
+		if !validName.MatchString(got) {
+			t.Errorf("SanitizeMetricName(%q) = %q is not a valid metric or label name", in, got)
+		}
+		if dec, want := unsanitize(t, got), ToSnakeCase(in); dec != want {
+			t.Errorf("SanitizeMetricName(%q) = %q decodes to %q, want %q", in, got, dec, want)
+		}
+	}
+}
+
+// unsanitize inverts SanitizeMetricName, which shows the mapping from
+// ToSnakeCase results is injective.
+func unsanitize(t *testing.T, name string) string {
+	t.Helper()
+	if name == "_" {
+		return ""
+	}
+	if len(name) > 1 && name[0] == '_' && name[1] >= '0' && name[1] <= '9' {
+		name = name[1:]
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		width := 0
+		switch name[i] {
+		case 'U':
+			width = 4
+		case 'V':
+			width = 6
+		default:
+			b.WriteByte(name[i])
+			i++
+			continue
+		}
+		r, err := strconv.ParseUint(name[i+1:i+1+width], 16, 32)
+		if err != nil {
+			t.Fatalf("bad escape in %q: %v", name, err)
+		}
+		b.WriteRune(rune(r))
+		i += 1 + width
+	}
+	return b.String()
+}
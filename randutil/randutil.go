@@ -0,0 +1,37 @@
+// Package randutil generates random values from crypto/rand, suitable for
+// nonces, secrets and identifiers that must not be guessable.
+package randutil
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrNegativeLength is returned when a negative length is requested.
+var ErrNegativeLength = errors.New("randutil: negative length")
+
+// Bytes returns n bytes read from crypto/rand.
+func Bytes(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrNegativeLength
+	}
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// RandomHexStringSecure returns a string of n lower-case hex characters
+// drawn from crypto/rand, carrying 4n bits of entropy.
+func RandomHexStringSecure(n int) (string, error) {
+	if n < 0 {
+		return "", ErrNegativeLength
+	}
+	b, err := Bytes((n + 1) / 2)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b)[:n], nil
+}
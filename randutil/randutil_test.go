@@ -0,0 +1,74 @@
+package randutil
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+var lowerHex = regexp.MustCompile(`^[0-9a-f]*$`)
+
+func TestRandomHexStringSecure(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 15, 16, 31, 32, 64} {
+		s, err := RandomHexStringSecure(n)
+		if err != nil {
+			t.Fatalf("RandomHexStringSecure(%d): %v", n, err)
+		}
+		if len(s) != n {
+			t.Errorf("RandomHexStringSecure(%d) has length %d", n, len(s))
+		}
+		if !lowerHex.MatchString(s) {
+			t.Errorf("RandomHexStringSecure(%d) = %q, not lower-case hex", n, s)
+		}
+	}
+}
+
+func TestRandomHexStringSecureUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		s, err := RandomHexStringSecure(32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[s] {
+			t.Fatalf("duplicate value %q after %d draws", s, i)
+		}
+		seen[s] = true
+	}
+}
+
+func TestRandomHexStringSecureUsesEveryDigit(t *testing.T) {
+	s, err := RandomHexStringSecure(4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	// Each digit is expected 256 times; far fewer means a broken source.
+	for _, r := range "0123456789abcdef" {
+		if counts[r] < 128 {
+			t.Errorf("digit %q appeared %d times in 4096", r, counts[r])
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	b, err := Bytes(32)
+	if err != nil || len(b) != 32 {
+		t.Fatalf("Bytes(32) = %d bytes, %v", len(b), err)
+	}
+	if b, err := Bytes(0); err != nil || len(b) != 0 {
+		t.Errorf("Bytes(0) = %v, %v", b, err)
+	}
+}
+
+func TestNegativeLength(t *testing.T) {
+	if _, err := RandomHexStringSecure(-1); !errors.Is(err, ErrNegativeLength) {
+		t.Errorf("RandomHexStringSecure(-1) error = %v, want ErrNegativeLength", err)
+	}
+	if _, err := Bytes(-1); !errors.Is(err, ErrNegativeLength) {
+		t.Errorf("Bytes(-1) error = %v, want ErrNegativeLength", err)
+	}
+}
@@ -0,0 +1,111 @@
+// Package recovery provides HTTP middleware that turns handler panics into
+// 500 responses instead of dropping the connection.
+package recovery
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+)
+
+// Options configures the middleware.
+type Options struct {
+	// OnPanic is called with the request, the recovered value and the
+	// goroutine's stack for every recovered panic, before the response is
+	// written. It is the place to log the stack, count panics and mark the
+	// active span as errored. When nil the panic is logged with the
+	// standard library log package.
+	OnPanic func(r *http.Request, v interface{}, stack []byte)
+}
+
+// Middleware returns a function wrapping a handler so that a panic in it
+// is recovered and reported to opts.OnPanic. If the handler has not yet
+// started the response, a 500 Internal Server Error is sent. Otherwise the
+// connection is aborted with http.ErrAbortHandler, so the client sees a
+// truncated response rather than a complete-looking one. Panics with
+// http.ErrAbortHandler itself are passed through untouched.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	onPanic := opts.OnPanic
+	if onPanic == nil {
+		onPanic = logPanic
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				onPanic(r, v, debug.Stack())
+				if rw.hijacked {
+					return
+				}
+				if rw.started {
+					panic(http.ErrAbortHandler)
+				}
+				h := w.Header()
+				h.Del("Content-Length")
+				h.Del("Content-Encoding")
+				h.Del("Content-Range")
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+func logPanic(r *http.Request, v interface{}, stack []byte) {
+	log.Printf("recovery: panic serving %s %s: %v\n%s", r.Method, r.URL.Path, v, stack)
+}
+
+// responseWriter records whether the response has started.
+type responseWriter struct {
+	http.ResponseWriter
+	started  bool
+	hijacked bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// Informational responses do not commit the final status.
+	if status >= 200 || status == http.StatusSwitchingProtocols {
+		w.started = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends buffered data to the client, which starts the response.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.started = true
+		f.Flush()
+	}
+}
+
+// Hijack lets protocols such as websockets take over the connection.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,166 @@
+package recovery
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type panicReport struct {
+	calls int
+	value interface{}
+	stack string
+	path  string
+}
+
+func (p *panicReport) options() Options {
+	return Options{OnPanic: func(r *http.Request, v interface{}, stack []byte) {
+		p.calls++
+		p.value = v
+		p.stack = string(stack)
+		p.path = r.URL.Path
+	}}
+}
+
+func serve(h http.Handler) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/snapshot", nil))
+	return w
+}
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Encoding", "gzip")
+	panic("nil map write")
+}
+
+func TestPanicBecomes500(t *testing.T) {
+	var rep panicReport
+	w := serve(Middleware(rep.options())(http.HandlerFunc(panickingHandler)))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != "Internal Server Error" {
+		t.Errorf("body = %q", got)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q left over from the handler", got)
+	}
+	if rep.calls != 1 || rep.value != "nil map write" || rep.path != "/snapshot" {
+		t.Errorf("OnPanic got %+v", rep)
+	}
+	if !strings.Contains(rep.stack, "panickingHandler") {
+		t.Errorf("stack does not include the panicking function:\n%s", rep.stack)
+	}
+}
+
+func TestNoPanicPassesThrough(t *testing.T) {
+	var rep panicReport
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("queued"))
+	})
+	w := serve(Middleware(rep.options())(h))
+	if w.Code != http.StatusAccepted || w.Body.String() != "queued" || rep.calls != 0 {
+		t.Errorf("status %d body %q OnPanic calls %d", w.Code, w.Body.String(), rep.calls)
+	}
+}
+
+func TestPanicAfterResponseStartedAborts(t *testing.T) {
+	for name, start := range map[string]func(http.ResponseWriter){
+		"write":       func(w http.ResponseWriter) { w.Write([]byte("partial")) },
+		"writeHeader": func(w http.ResponseWriter) { w.WriteHeader(http.StatusOK) },
+		"flush":       func(w http.ResponseWriter) { w.(http.Flusher).Flush() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var rep panicReport
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				start(w)
+				panic("late failure")
+			})
+			w := httptest.NewRecorder()
+			defer func() {
+				if v := recover(); v != http.ErrAbortHandler {
+					t.Errorf("recovered %v, want http.ErrAbortHandler", v)
+				}
+				if rep.calls != 1 || rep.value != "late failure" {
+					t.Errorf("OnPanic got %+v", rep)
+				}
+				if w.Code == http.StatusInternalServerError {
+					t.Error("500 written after the response had started")
+				}
+			}()
+			Middleware(rep.options())(h).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	}
+}
+
+func TestInformationalStatusDoesNotStartResponse(t *testing.T) {
+	var rep panicReport
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusEarlyHints)
+		panic("after hints")
+	})
+	srv := httptest.NewServer(Middleware(rep.options())(h))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", resp.StatusCode)
+	}
+}
+
+func TestErrAbortHandlerPassesThrough(t *testing.T) {
+	var rep panicReport
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", v)
+		}
+		if rep.calls != 0 {
+			t.Error("OnPanic called for http.ErrAbortHandler")
+		}
+	}()
+	serve(Middleware(rep.options())(h))
+}
+
+func TestDefaultLogsPanic(t *testing.T) {
+	var buf bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(prev)
+
+	w := serve(Middleware(Options{})(http.HandlerFunc(panickingHandler)))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "panic serving GET /snapshot: nil map write") || !strings.Contains(out, "panickingHandler") {
+		t.Errorf("log output missing panic details:\n%s", out)
+	}
+}
+
+func TestServerKeepsRunning(t *testing.T) {
+	srv := httptest.NewServer(Middleware(Options{OnPanic: func(*http.Request, interface{}, []byte) {}})(http.HandlerFunc(panickingHandler)))
+	defer srv.Close()
+	for i := 0; i < 3; i++ {
+		resp, err := srv.Client().Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("request %d: status = %d, want 500", i, resp.StatusCode)
+		}
+	}
+}
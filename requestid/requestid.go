@@ -0,0 +1,86 @@
+// Package requestid tags each HTTP request with an ID that is carried in
+// the request context and echoed to the client, so log lines and error
+// reports from one request can be correlated.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/adcosta-hbo/snowflake-monitor/randutil"
+)
+
+// Header is the default header carrying the request ID.
+const Header = "X-Request-Id"
+
+// maxLen bounds an incoming ID; longer values are replaced.
+const maxLen = 128
+
+// Options configures the middleware.
+type Options struct {
+	// Header names the request and response header carrying the ID.
+	// Empty means Header.
+	Header string
+	// Generate returns a new ID. When nil, IDs are 32 hex characters from
+	// crypto/rand.
+	Generate func() (string, error)
+}
+
+// Middleware returns a function wrapping a handler so that every request
+// has an ID. An ID supplied by the client or an upstream proxy is kept if
+// it is at most 128 printable ASCII characters without spaces; otherwise a
+// new one is generated. The ID is stored in the request context, where
+// FromContext retrieves it, and set on the response header.
+//
+// If generating an ID fails the request is served without one rather than
+// rejected.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	header := opts.Header
+	if header == "" {
+		header = Header
+	}
+	generate := opts.Generate
+	if generate == nil {
+		generate = func() (string, error) { return randutil.RandomHexStringSecure(32) }
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if !valid(id) {
+				var err error
+				if id, err = generate(); err != nil || !valid(id) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			w.Header().Set(header, id)
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+		})
+	}
+}
+
+// valid reports whether id is safe to log and echo back.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or "" if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,119 @@
+package requestid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var generated = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// serve runs req through the middleware and returns the response together
+// with the ID the handler saw in its context.
+func serve(opts Options, req *http.Request) (*httptest.ResponseRecorder, string) {
+	var seen string
+	h := Middleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w, seen
+}
+
+func TestGeneratesWhenAbsent(t *testing.T) {
+	w, seen := serve(Options{}, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !generated.MatchString(seen) {
+		t.Fatalf("context ID = %q, want 32 hex characters", seen)
+	}
+	if got := w.Header().Get(Header); got != seen {
+		t.Errorf("response %s = %q, want %q", Header, got, seen)
+	}
+
+	_, other := serve(Options{}, httptest.NewRequest(http.MethodGet, "/", nil))
+	if other == seen {
+		t.Errorf("two requests got the same ID %q", seen)
+	}
+}
+
+func TestKeepsIncoming(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, "lb-7f3a-0001")
+	w, seen := serve(Options{}, req)
+	if seen != "lb-7f3a-0001" || w.Header().Get(Header) != "lb-7f3a-0001" {
+		t.Errorf("context %q, response %q; want the incoming ID", seen, w.Header().Get(Header))
+	}
+}
+
+func TestReplacesInvalidIncoming(t *testing.T) {
+	for _, id := range []string{
+		"has space",
+		"new\nline",
+		"café",
+		strings.Repeat("a", maxLen+1),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(Header, id)
+		w, seen := serve(Options{}, req)
+		if !generated.MatchString(seen) || w.Header().Get(Header) != seen {
+			t.Errorf("incoming %q: context %q, response %q; want a generated ID", id, seen, w.Header().Get(Header))
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, strings.Repeat("a", maxLen))
+	if _, seen := serve(Options{}, req); seen != strings.Repeat("a", maxLen) {
+		t.Errorf("ID of exactly %d characters was replaced", maxLen)
+	}
+}
+
+func TestCustomHeaderAndGenerator(t *testing.T) {
+	opts := Options{
+		Header:   "X-Correlation-Id",
+		Generate: func() (string, error) { return "fixed", nil },
+	}
+	w, seen := serve(opts, httptest.NewRequest(http.MethodGet, "/", nil))
+	if seen != "fixed" || w.Header().Get("X-Correlation-Id") != "fixed" {
+		t.Errorf("context %q, response %q; want fixed", seen, w.Header().Get("X-Correlation-Id"))
+	}
+	if got := w.Header().Get(Header); got != "" {
+		t.Errorf("default header set to %q with a custom header configured", got)
+	}
+}
+
+func TestGenerateFailureServesWithoutID(t *testing.T) {
+	for name, gen := range map[string]func() (string, error){
+		"error":   func() (string, error) { return "", errors.New("entropy unavailable") },
+		"invalid": func() (string, error) { return "not valid", nil },
+	} {
+		t.Run(name, func(t *testing.T) {
+			called := false
+			h := Middleware(Options{Generate: gen})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				if id := FromContext(r.Context()); id != "" {
+					t.Errorf("context ID = %q, want none", id)
+				}
+			}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if !called {
+				t.Fatal("handler not called")
+			}
+			if got := w.Header().Get(Header); got != "" {
+				t.Errorf("response %s = %q, want none", Header, got)
+			}
+		})
+	}
+}
+
+func TestFromContextEmpty(t *testing.T) {
+	if id := FromContext(context.Background()); id != "" {
+		t.Errorf("FromContext(Background) = %q", id)
+	}
+	if id := FromContext(NewContext(context.Background(), "abc")); id != "abc" {
+		t.Errorf("FromContext = %q, want abc", id)
+	}
+}
@@ -0,0 +1,72 @@
+package sliceutil
+
+// OrderedSet is a set that remembers insertion order, so Slice returns the
+// same ordering for the same sequence of Adds. The zero value is an empty
+// set ready to use. An OrderedSet is not safe for concurrent use.
+type OrderedSet[T comparable] struct {
+	index map[T]int
+	items []T
+}
+
+// NewOrderedSet returns a set holding items, with duplicates dropped after
+// their first occurrence.
+func NewOrderedSet[T comparable](items ...T) *OrderedSet[T] {
+	s := &OrderedSet[T]{}
+	s.Add(items...)
+	return s
+}
+
+// Add appends each item not already in the set and reports how many were
+// added. Re-adding an item does not change its position.
+func (s *OrderedSet[T]) Add(items ...T) int {
+	if s.index == nil {
+		s.index = make(map[T]int, len(items))
+	}
+	added := 0
+	for _, v := range items {
+		if _, ok := s.index[v]; ok {
+			continue
+		}
+		s.index[v] = len(s.items)
+		s.items = append(s.items, v)
+		added++
+	}
+	return added
+}
+
+// Remove deletes item from the set, keeping the relative order of the rest,
+// and reports whether it was present.
+func (s *OrderedSet[T]) Remove(item T) bool {
+	i, ok := s.index[item]
+	if !ok {
+		return false
+	}
+	delete(s.index, item)
+	copy(s.items[i:], s.items[i+1:])
+	var zero T
+	s.items[len(s.items)-1] = zero
+	s.items = s.items[:len(s.items)-1]
+	for j := i; j < len(s.items); j++ {
+		s.index[s.items[j]] = j
+	}
+	return true
+}
+
+// Contains reports whether item is in the set.
+func (s *OrderedSet[T]) Contains(item T) bool {
+	_, ok := s.index[item]
+	return ok
+}
+
+// Len returns the number of items in the set.
+func (s *OrderedSet[T]) Len() int {
+	return len(s.items)
+}
+
+// Slice returns the items in insertion order. The result is a copy and may
+// be modified freely.
+func (s *OrderedSet[T]) Slice() []T {
+	out := make([]T, len(s.items))
+	copy(out, s.items)
+	return out
+}
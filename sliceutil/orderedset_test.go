@@ -0,0 +1,63 @@
+package sliceutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderedSetAdd(t *testing.T) {
+	s := NewOrderedSet("region", "warehouse", "region")
+	if n := s.Add("db", "warehouse", "schema", "db"); n != 2 {
+		t.Errorf("Add returned %d, want 2", n)
+	}
+	want := []string{"region", "warehouse", "db", "schema"}
+	if got := s.Slice(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Slice = %v, want %v", got, want)
+	}
+	if s.Len() != len(want) {
+		t.Errorf("Len = %d, want %d", s.Len(), len(want))
+	}
+	if !s.Contains("db") || s.Contains("missing") {
+		t.Errorf("Contains gave wrong answers for %v", s.Slice())
+	}
+}
+
+func TestOrderedSetZeroValue(t *testing.T) {
+	var s OrderedSet[int]
+	if s.Contains(1) || s.Remove(1) || s.Len() != 0 || len(s.Slice()) != 0 {
+		t.Fatal("zero value is not an empty set")
+	}
+	s.Add(3, 1, 2)
+	if got, want := s.Slice(), []int{3, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Slice = %v, want %v", got, want)
+	}
+}
+
+func TestOrderedSetRemove(t *testing.T) {
+	s := NewOrderedSet(1, 2, 3, 4)
+	if !s.Remove(2) {
+		t.Fatal("Remove(2) = false, want true")
+	}
+	if s.Remove(2) {
+		t.Fatal("second Remove(2) = true, want false")
+	}
+	s.Add(2)
+	if got, want := s.Slice(), []int{1, 3, 4, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Slice = %v, want %v", got, want)
+	}
+	if !s.Remove(3) || !s.Contains(4) || !s.Remove(4) {
+		t.Fatal("index out of sync after Remove")
+	}
+	if got, want := s.Slice(), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Slice = %v, want %v", got, want)
+	}
+}
+
+func TestOrderedSetSliceIsCopy(t *testing.T) {
+	s := NewOrderedSet("a", "b")
+	out := s.Slice()
+	out[0] = "z"
+	if s.Slice()[0] != "a" {
+		t.Error("modifying Slice result changed the set")
+	}
+}
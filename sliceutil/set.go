@@ -0,0 +1,99 @@
+package sliceutil
+
+// Set is an unordered set of comparable values. Use OrderedSet when a
+// stable iteration order is needed. A nil Set is empty and may be read but
+// not added to; use NewSet or make.
+type Set[T comparable] map[T]struct{}
+
+// NewSet returns a set holding items.
+func NewSet[T comparable](items ...T) Set[T] {
+	s := make(Set[T], len(items))
+	s.Add(items...)
+	return s
+}
+
+// Add inserts items into the set.
+func (s Set[T]) Add(items ...T) {
+	for _, v := range items {
+		s[v] = struct{}{}
+	}
+}
+
+// Remove deletes items from the set.
+func (s Set[T]) Remove(items ...T) {
+	for _, v := range items {
+		delete(s, v)
+	}
+}
+
+// Contains reports whether item is in the set.
+func (s Set[T]) Contains(item T) bool {
+	_, ok := s[item]
+	return ok
+}
+
+// Len returns the number of items in the set.
+func (s Set[T]) Len() int {
+	return len(s)
+}
+
+// Slice returns the items of the set in unspecified order.
+func (s Set[T]) Slice() []T {
+	out := make([]T, 0, len(s))
+	for v := range s {
+		out = append(out, v)
+	}
+	return out
+}
+
+// Union returns a new set holding the items in s or other.
+func (s Set[T]) Union(other Set[T]) Set[T] {
+	out := make(Set[T], len(s)+len(other))
+	for v := range s {
+		out[v] = struct{}{}
+	}
+	for v := range other {
+		out[v] = struct{}{}
+	}
+	return out
+}
+
+// Intersection returns a new set holding the items in both s and other.
+func (s Set[T]) Intersection(other Set[T]) Set[T] {
+	small, large := s, other
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	out := make(Set[T])
+	for v := range small {
+		if _, ok := large[v]; ok {
+			out[v] = struct{}{}
+		}
+	}
+	return out
+}
+
+// Difference returns a new set holding the items in s that are not in
+// other.
+func (s Set[T]) Difference(other Set[T]) Set[T] {
+	out := make(Set[T])
+	for v := range s {
+		if _, ok := other[v]; !ok {
+			out[v] = struct{}{}
+		}
+	}
+	return out
+}
+
+// Equal reports whether s and other hold the same items.
+func (s Set[T]) Equal(other Set[T]) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for v := range s {
+		if _, ok := other[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
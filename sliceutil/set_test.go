@@ -0,0 +1,79 @@
+package sliceutil
+
+import (
+	"sort"
+	"testing"
+)
+
+func sorted(s Set[string]) []string {
+	out := s.Slice()
+	sort.Strings(out)
+	return out
+}
+
+func TestSetBasics(t *testing.T) {
+	s := NewSet("a", "b", "a")
+	if s.Len() != 2 || !s.Contains("a") || s.Contains("c") {
+		t.Fatalf("NewSet = %v", sorted(s))
+	}
+	s.Add("c", "d")
+	s.Remove("a", "missing")
+	if got := sorted(s); len(got) != 3 || got[0] != "b" || got[1] != "c" || got[2] != "d" {
+		t.Errorf("after Add/Remove = %v, want [b c d]", got)
+	}
+
+	var empty Set[string]
+	if empty.Len() != 0 || empty.Contains("a") || len(empty.Slice()) != 0 {
+		t.Error("nil Set is not empty")
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	a := NewSet("x", "y", "z")
+	b := NewSet("y", "z", "w")
+	var none Set[string]
+
+	tests := []struct {
+		name string
+		got  Set[string]
+		want Set[string]
+	}{
+		{"union", a.Union(b), NewSet("w", "x", "y", "z")},
+		{"intersection", a.Intersection(b), NewSet("y", "z")},
+		{"intersection reversed", b.Intersection(a), NewSet("y", "z")},
+		{"difference", a.Difference(b), NewSet("x")},
+		{"difference reversed", b.Difference(a), NewSet("w")},
+		{"union with nil", a.Union(none), a},
+		{"intersection with nil", a.Intersection(none), NewSet[string]()},
+		{"difference with nil", a.Difference(none), a},
+		{"nil difference", none.Difference(a), NewSet[string]()},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, sorted(tt.got), sorted(tt.want))
+		}
+	}
+
+	// Results are new sets; the operands are unchanged.
+	if !a.Equal(NewSet("x", "y", "z")) || !b.Equal(NewSet("y", "z", "w")) {
+		t.Error("operation modified an operand")
+	}
+}
+
+func TestSetEqual(t *testing.T) {
+	tests := []struct {
+		a, b Set[int]
+		want bool
+	}{
+		{NewSet(1, 2, 3), NewSet(3, 2, 1), true},
+		{NewSet(1, 2), NewSet(1, 2, 3), false},
+		{NewSet(1, 2, 4), NewSet(1, 2, 3), false},
+		{nil, NewSet[int](), true},
+		{nil, nil, true},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Equal(tt.b); got != tt.want {
+			t.Errorf("%v.Equal(%v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Package sliceutil provides generic helpers for working with slices.
+package sliceutil
+
+// Chunk splits s into consecutive sub-slices of at most size elements. The
+// final chunk holds the remainder. Chunks share the backing array of s. A
+// size less than 1 returns nil.
+func Chunk[T any](s []T, size int) [][]T {
+	if size < 1 || len(s) == 0 {
+		return nil
+	}
+	chunks := make([][]T, 0, (len(s)+size-1)/size)
+	for size < len(s) {
+		s, chunks = s[size:], append(chunks, s[0:size:size])
+	}
+	return append(chunks, s)
+}
+
+// Map returns a new slice holding the result of applying fn to each element
+// of s.
+func Map[T, U any](s []T, fn func(T) U) []U {
+	if s == nil {
+		return nil
+	}
+	out := make([]U, len(s))
+	for i, v := range s {
+		out[i] = fn(v)
+	}
+	return out
+}
+
+// Filter returns a new slice holding the elements of s for which keep
+// returns true, in their original order.
+func Filter[T any](s []T, keep func(T) bool) []T {
+	var out []T
+	for _, v := range s {
+		if keep(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Unique returns a new slice holding the elements of s with duplicates
+// removed, keeping the first occurrence of each.
+func Unique[T comparable](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	seen := make(map[T]struct{}, len(s))
+	out := make([]T, 0, len(s))
+	for _, v := range s {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+	return out
+}
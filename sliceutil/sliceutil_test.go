@@ -0,0 +1,72 @@
+package sliceutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []int
+		size int
+		want [][]int
+	}{
+		{"nil input", nil, 2, nil},
+		{"empty input", []int{}, 2, nil},
+		{"zero size", []int{1, 2}, 0, nil},
+		{"negative size", []int{1, 2}, -1, nil},
+		{"exact multiple", []int{1, 2, 3, 4}, 2, [][]int{{1, 2}, {3, 4}}},
+		{"remainder", []int{1, 2, 3, 4, 5}, 2, [][]int{{1, 2}, {3, 4}, {5}}},
+		{"size larger than input", []int{1, 2}, 5, [][]int{{1, 2}}},
+		{"size one", []int{1, 2, 3}, 1, [][]int{{1}, {2}, {3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Chunk(tt.in, tt.size)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chunk(%v, %d) = %v, want %v", tt.in, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkCapsCapacity(t *testing.T) {
+	in := []int{1, 2, 3, 4, 5}
+	chunks := Chunk(in, 2)
+	for i, c := range chunks[:len(chunks)-1] {
+		if cap(c) != len(c) {
+			t.Errorf("chunk %d: cap = %d, want %d", i, cap(c), len(c))
+		}
+	}
+
+	// Appending to a chunk must not overwrite the next one.
+	chunks[0] = append(chunks[0], 99)
+	if in[2] != 3 || chunks[1][0] != 3 {
+		t.Errorf("append to chunk 0 clobbered input: in = %v, chunk 1 = %v", in, chunks[1])
+	}
+}
+
+func TestMap(t *testing.T) {
+	got := Map([]int{1, 2, 3}, func(i int) int { return i * 10 })
+	if want := []int{10, 20, 30}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Map = %v, want %v", got, want)
+	}
+	if got := Map(nil, func(i int) int { return i }); got != nil {
+		t.Errorf("Map(nil) = %v, want nil", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	got := Filter([]int{1, 2, 3, 4}, func(i int) bool { return i%2 == 0 })
+	if want := []int{2, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter = %v, want %v", got, want)
+	}
+}
+
+func TestUnique(t *testing.T) {
+	got := Unique([]string{"b", "a", "b", "c", "a"})
+	if want := []string{"b", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unique = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+// Package textutil shortens strings for display and logging without
+// splitting multibyte UTF-8 characters.
+//
+// Lengths are counted in runes, not bytes or user-perceived characters, so
+// a letter followed by a combining mark counts as two.
+package textutil
+
+import "unicode/utf8"
+
+// DefaultEllipsis is appended by Elide.
+const DefaultEllipsis = "..."
+
+// TruncateRunes returns the first n runes of s, or s itself if it has at
+// most n runes. The cut never falls inside a multibyte character, so valid
+// UTF-8 stays valid. A negative n is treated as zero.
+func TruncateRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// Elide shortens s to at most max runes, replacing the removed tail with
+// DefaultEllipsis.
+func Elide(s string, max int) string {
+	return ElideWith(s, max, DefaultEllipsis)
+}
+
+// ElideWith shortens s to at most max runes, replacing the removed tail
+// with ellipsis, e.g. "…". Strings of at most max runes are returned
+// unchanged. If max is too small to hold the ellipsis, the ellipsis itself
+// is truncated to max runes.
+func ElideWith(s string, max int, ellipsis string) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	room := max - utf8.RuneCountInString(ellipsis)
+	if room < 0 {
+		return TruncateRunes(ellipsis, max)
+	}
+	return TruncateRunes(s, room) + ellipsis
+}
@@ -0,0 +1,100 @@
+package textutil
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"", 3, ""},
+		{"abc", 0, ""},
+		{"abc", -1, ""},
+		{"abc", 2, "ab"},
+		{"abc", 3, "abc"},
+		{"abc", 10, "abc"},
+		{"héllo", 2, "hé"},
+		{"日本語のテキスト", 3, "日本語"},
+		{"😀😁😂", 2, "😀😁"},
+		{"a😀b", 2, "a😀"},
+		{"cafe\u0301", 4, "cafe"},
+	}
+	for _, tt := range tests {
+		got := TruncateRunes(tt.in, tt.n)
+		if got != tt.want {
+			t.Errorf("TruncateRunes(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("TruncateRunes(%q, %d) = %q is not valid UTF-8", tt.in, tt.n, got)
+		}
+	}
+}
+
+func TestElide(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"warehouse_name", 10, "warehou..."},
+		{"ünïcödé strïng", 8, "ünïcö..."},
+		{"日本語のテキストです", 6, "日本語..."},
+		{"😀😁😂🤣😃😄", 5, "😀😁..."},
+		{"abcdef", 2, ".."},
+		{"abcdef", 0, ""},
+	}
+	for _, tt := range tests {
+		got := Elide(tt.in, tt.max)
+		if got != tt.want {
+			t.Errorf("Elide(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("Elide(%q, %d) = %q is not valid UTF-8", tt.in, tt.max, got)
+		}
+		if n := utf8.RuneCountInString(got); n > tt.max {
+			t.Errorf("Elide(%q, %d) has %d runes", tt.in, tt.max, n)
+		}
+	}
+}
+
+func TestElideWith(t *testing.T) {
+	tests := []struct {
+		in       string
+		max      int
+		ellipsis string
+		want     string
+	}{
+		{"warehouse_name", 10, "…", "warehouse…"},
+		{"日本語のテキスト", 4, "…", "日本語…"},
+		{"größenordnung", 6, "…", "größe…"},
+		{"abcdef", 4, "", "abcd"},
+		{"abcdef", 1, "……", "…"},
+		{"abc", 3, "…", "abc"},
+	}
+	for _, tt := range tests {
+		got := ElideWith(tt.in, tt.max, tt.ellipsis)
+		if got != tt.want {
+			t.Errorf("ElideWith(%q, %d, %q) = %q, want %q", tt.in, tt.max, tt.ellipsis, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("ElideWith(%q, %d, %q) = %q is not valid UTF-8", tt.in, tt.max, tt.ellipsis, got)
+		}
+	}
+}
+
+func TestElideNeverSplitsRunes(t *testing.T) {
+	s := "aé日😀"
+	for max := 0; max <= utf8.RuneCountInString(s)+1; max++ {
+		for _, ellipsis := range []string{"", ".", "…", "😀"} {
+			if got := ElideWith(s, max, ellipsis); !utf8.ValidString(got) {
+				t.Errorf("ElideWith(%q, %d, %q) = %q is not valid UTF-8", s, max, ellipsis, got)
+			}
+		}
+	}
+}